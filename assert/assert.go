@@ -2,6 +2,7 @@
 package assert
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -65,3 +66,22 @@ func False(t *testing.T, value bool, errorMessage ...string) {
 	}
 	fail(t, "Unexpected true", errorMessage...)
 }
+
+// NoError asserts that the error is nil.
+func NoError(t *testing.T, err error, errorMessage ...string) {
+	t.Helper()
+	if err == nil {
+		return
+	}
+	fail(t, fmt.Sprintf("Unexpected error: %v", err), errorMessage...)
+}
+
+// ErrorIs asserts that the error matches the target error.
+func ErrorIs(t *testing.T, err, target error, errorMessage ...string) {
+	t.Helper()
+	if errors.Is(err, target) {
+		return
+	}
+	msg := fmt.Sprintf("Error mismatch: \nexpected: %v\nactual  : %v", target, err)
+	fail(t, msg, errorMessage...)
+}
@@ -162,6 +162,40 @@ func BenchmarkReadHashMapString(b *testing.B) {
 	})
 }
 
+func BenchmarkReadFrozenMapUint(b *testing.B) {
+	m := setupHashMap(b).Freeze()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for i := uintptr(0); i < benchmarkItemCount; i++ {
+				j, _ := m.Get(i)
+				if j != i {
+					b.Fail()
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkReadFrozenMapString(b *testing.B) {
+	hm, keys := setupHashMapString(b)
+	m := hm.Freeze()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for i := 0; i < benchmarkItemCount; i++ {
+				s := keys[i]
+				sVal, _ := m.Get(s)
+				if sVal != s {
+					b.Fail()
+				}
+			}
+		}
+	})
+}
+
 func BenchmarkReadHaxMapUint(b *testing.B) {
 	m := setupHaxMap(b)
 	b.ResetTimer()
@@ -413,6 +447,25 @@ func BenchmarkWriteHashMapUint(b *testing.B) {
 	}
 }
 
+func BenchmarkLoadHashMapSetUint(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		m := hashmap.New[uintptr, uintptr]()
+		for i := uintptr(0); i < benchmarkItemCount; i++ {
+			m.Set(i, i)
+		}
+	}
+}
+
+func BenchmarkLoadHashMapBuilderUint(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		builder := hashmap.NewBuilder[uintptr, uintptr](benchmarkItemCount)
+		for i := uintptr(0); i < benchmarkItemCount; i++ {
+			builder.Add(i, i)
+		}
+		_ = builder.Build()
+	}
+}
+
 func BenchmarkWriteGoMapMutexUint(b *testing.B) {
 	m := make(map[uintptr]uintptr)
 	l := &sync.RWMutex{}
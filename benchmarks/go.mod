@@ -1,6 +1,6 @@
 module github.com/cornelk/hashmap/benchmarks
 
-go 1.19
+go 1.22
 
 replace github.com/cornelk/hashmap => ../
 
@@ -0,0 +1,108 @@
+package hashmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// binaryFormatVersion is the version of the encoding created by MarshalBinary.
+const binaryFormatVersion = 1
+
+var errUnsupportedFormat = errors.New("unsupported format version")
+
+// MarshalBinary encodes the map into a binary form. The encoding starts with a format version,
+// the kind of the key type and the element count, followed by all elements in the order
+// of their hashed keys. Keys use a compact fixed width encoding for numeric kinds,
+// values are encoded using the value codec of the map.
+func (m *Map[Key, Value]) MarshalBinary() ([]byte, error) {
+	keyCodec, _ := newPrimitiveCodec[Key]() // all hashable types are supported
+	// a zero value map is encoded with an element count of 0
+	if m.linkedList == nil {
+		return []byte{binaryFormatVersion, byte(keyCodec.kind), 0}, nil
+	}
+	valueCodec := m.getValueCodec()
+
+	var (
+		records []byte
+		value   []byte
+		err     error
+		count   uint64
+	)
+	m.Range(func(key Key, v Value) bool {
+		value, err = valueCodec.AppendValue(value[:0], v)
+		if err != nil {
+			err = fmt.Errorf("encoding value of key %v: %w", key, err)
+			return false
+		}
+
+		records = keyCodec.append(records, key)
+		records = binary.AppendUvarint(records, uint64(len(value)))
+		records = append(records, value...)
+		count++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, 2+binary.MaxVarintLen64+len(records))
+	data = append(data, binaryFormatVersion, byte(keyCodec.kind))
+	data = binary.AppendUvarint(data, count)
+	return append(data, records...), nil
+}
+
+// UnmarshalBinary adds all elements of the binary encoding that was created by MarshalBinary
+// to the map, existing elements are kept. A zero value map gets initialized.
+func (m *Map[Key, Value]) UnmarshalBinary(data []byte) error {
+	keyCodec, _ := newPrimitiveCodec[Key]()
+	valueCodec := m.getValueCodec()
+
+	if len(data) < 2 {
+		return errShortBuffer
+	}
+	if data[0] != binaryFormatVersion {
+		return fmt.Errorf("%w %d", errUnsupportedFormat, data[0])
+	}
+	if kind := reflect.Kind(data[1]); kind != keyCodec.kind {
+		return fmt.Errorf("key kind %s does not match map key kind %s", kind, keyCodec.kind)
+	}
+	count, n := binary.Uvarint(data[2:])
+	if n <= 0 {
+		return errShortBuffer
+	}
+	data = data[2+n:]
+
+	m.reserve(int(min(count, uint64(len(data))))) // limit the count for corrupted input
+	for ; count > 0; count-- {
+		key, n, err := keyCodec.decode(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+
+		length, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < length {
+			return errShortBuffer
+		}
+		value, err := valueCodec.DecodeValue(data[n : n+int(length)])
+		if err != nil {
+			return fmt.Errorf("decoding value of key %v: %w", key, err)
+		}
+		data = data[n+int(length):]
+
+		m.Set(key, value)
+	}
+	return nil
+}
+
+// GobEncode encodes the map for encoding/gob using MarshalBinary.
+func (m *Map[Key, Value]) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
+}
+
+// GobDecode decodes a map that was encoded by GobEncode.
+func (m *Map[Key, Value]) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
+}
@@ -0,0 +1,122 @@
+package hashmap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strconv"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	t.Parallel()
+	m := New[int16, string]()
+	for i := int16(-50); i < 50; i++ {
+		m.Set(i, strconv.Itoa(int(i)))
+	}
+
+	data, err := m.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := New[int16, string]()
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, 100, decoded.Len())
+	for i := int16(-50); i < 50; i++ {
+		value, ok := decoded.Get(i)
+		assert.True(t, ok)
+		assert.Equal(t, strconv.Itoa(int(i)), value)
+	}
+
+	other := New[uint16, string]()
+	assert.True(t, other.UnmarshalBinary(data) != nil) // key kind mismatch
+	assert.True(t, decoded.UnmarshalBinary(data[:len(data)-1]) != nil)
+}
+
+func TestMarshalBinaryCompactKeys(t *testing.T) {
+	t.Parallel()
+	m := New[uint32, uint8]()
+	m.Set(1, 2)
+
+	data, err := m.MarshalBinary()
+	assert.NoError(t, err)
+	// version, key kind, count, 4 byte key, value length, 1 byte value
+	assert.Equal(t, 9, len(data))
+}
+
+type gobPayload struct {
+	Name  string
+	Items *Map[string, []int]
+}
+
+func TestGobEncoding(t *testing.T) {
+	t.Parallel()
+	payload := gobPayload{
+		Name:  "test",
+		Items: New[string, []int](),
+	}
+	payload.Items.Set("a", []int{1, 2, 3})
+	payload.Items.Set("b", []int{4})
+
+	var buf bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(payload))
+
+	var decoded gobPayload
+	assert.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	assert.Equal(t, "test", decoded.Name)
+	assert.Equal(t, 2, decoded.Items.Len())
+	value, ok := decoded.Items.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []int{1, 2, 3}, value)
+}
+
+// gobValuePayload is a struct for testing that embeds a map by value.
+type gobValuePayload struct {
+	Name  string
+	Items Map[string, int]
+}
+
+func TestBinaryZeroValue(t *testing.T) {
+	t.Parallel()
+	var m Map[string, int]
+	data, err := m.MarshalBinary()
+	assert.NoError(t, err)
+
+	var decoded Map[string, int]
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, 0, decoded.Len())
+
+	var buf bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(&gobValuePayload{Name: "test"}))
+	var payload gobValuePayload
+	assert.NoError(t, gob.NewDecoder(&buf).Decode(&payload))
+	assert.Equal(t, "test", payload.Name)
+	assert.Equal(t, 0, payload.Items.Len())
+}
+
+// upperCodec is a value codec for testing that stores strings in upper case.
+type upperCodec struct{}
+
+func (upperCodec) AppendValue(buf []byte, value string) ([]byte, error) {
+	return append(buf, bytes.ToUpper([]byte(value))...), nil
+}
+
+func (upperCodec) DecodeValue(data []byte) (string, error) {
+	return string(data), nil
+}
+
+func TestCustomValueCodec(t *testing.T) {
+	t.Parallel()
+	m := New[int, string]()
+	m.SetValueCodec(upperCodec{})
+	m.Set(1, "abc")
+
+	data, err := m.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := New[int, string]()
+	decoded.SetValueCodec(upperCodec{})
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	value, _ := decoded.Get(1)
+	assert.Equal(t, "ABC", value)
+}
@@ -0,0 +1,79 @@
+package hashmap
+
+// SetMemoryBudget enables the eviction of elements once the total weight of all elements
+// exceeds the budget. The sizer returns the weight of an element, for example its size in bytes.
+// Elements are evicted in hash order, starting after the last evicted element, which
+// approximates a random eviction. It has to be called before the map is used.
+func (m *Map[Key, Value]) SetMemoryBudget(sizer func(Key, Value) int64, budget int64) {
+	m.sizer = sizer
+	m.budget = budget
+	m.enableElementMeta()
+}
+
+// Weight returns the total weight of all elements within the map as reported by the sizer.
+func (m *Map[Key, Value]) Weight() int64 {
+	return m.weight.Load()
+}
+
+// setWeight updates the weight of the element and the total weight of the map.
+func (m *Map[Key, Value]) setWeight(element *ListElement[Key, Value], weight int64) {
+	meta := element.meta()
+	previous := meta.weight.Swap(weight)
+	m.weight.Add(weight - previous)
+
+	if element.deleted.Load() != 0 { // the element got deleted concurrently, release its weight
+		m.weight.Add(-meta.weight.Swap(0))
+	}
+}
+
+// evictOverBudget evicts elements until the total weight is within the budget.
+// The given element that was just written is only evicted if no other element is left.
+// Only one goroutine evicts at a time, concurrent calls return immediately.
+func (m *Map[Key, Value]) evictOverBudget(keep *ListElement[Key, Value]) {
+	if m.weight.Load() <= m.budget || !m.evicting.CompareAndSwap(0, 1) {
+		return
+	}
+	defer m.evicting.Store(0)
+
+	for m.weight.Load() > m.budget {
+		element := m.evictionCandidate(keep)
+		if element == nil {
+			return
+		}
+
+		m.evictCursor.Store(element.keyHash + 1)
+		if element.deleted.Load() != 0 {
+			continue // deleted concurrently
+		}
+		if m.removeElement(element) {
+			m.evictions.Add(1)
+		}
+	}
+}
+
+// evictionCandidate returns the first element with a hash key not smaller than the eviction
+// cursor, wrapping around to the start of the list at the end.
+func (m *Map[Key, Value]) evictionCandidate(keep *ListElement[Key, Value]) *ListElement[Key, Value] {
+	cursor := m.evictCursor.Load()
+	store := m.store.Load()
+
+	var element *ListElement[Key, Value]
+	// find the first used index slot starting at the slot of the cursor
+	for slot := cursor >> store.keyShifts; slot < uintptr(len(store.index)) && element == nil; slot++ {
+		element = store.item(slot << store.keyShifts)
+	}
+
+	for ; element != nil; element = element.Next() {
+		if element.keyHash >= cursor && element != keep {
+			return element
+		}
+	}
+
+	// wrap around to the start of the list
+	for element = m.linkedList.First(); element != nil; element = element.Next() {
+		if element != keep {
+			return element
+		}
+	}
+	return keep
+}
@@ -0,0 +1,56 @@
+package hashmap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func stringSizer(_ int, value string) int64 {
+	return int64(len(value))
+}
+
+func TestMemoryBudget(t *testing.T) {
+	t.Parallel()
+	m := New[int, string]()
+	m.SetMemoryBudget(stringSizer, 100)
+
+	m.Set(1, strings.Repeat("a", 40))
+	assert.True(t, m.Insert(2, strings.Repeat("b", 40)))
+	m.GetOrInsert(3, strings.Repeat("c", 10))
+	assert.Equal(t, 90, m.Weight())
+
+	m.Set(1, strings.Repeat("a", 20)) // update reduces the weight
+	assert.Equal(t, 70, m.Weight())
+
+	assert.True(t, m.Del(3))
+	assert.Equal(t, 60, m.Weight())
+
+	m.Set(4, strings.Repeat("d", 50))
+	assert.True(t, m.Weight() <= 100)
+	_, ok := m.Get(4) // the written element is not evicted
+	assert.True(t, ok)
+
+	stats := m.Stats()
+	assert.Equal(t, m.Weight(), stats.Weight)
+	assert.Equal(t, m.Len(), stats.Len)
+	assert.True(t, stats.Evictions > 0)
+}
+
+func TestMemoryBudgetEvictsAll(t *testing.T) {
+	t.Parallel()
+	m := New[int, string]()
+	m.SetMemoryBudget(stringSizer, 10)
+
+	for i := range 100 {
+		m.Set(i, "12345")
+		assert.True(t, m.Weight() <= 10)
+	}
+	assert.Equal(t, 2, m.Len())
+	assert.Equal(t, 98, m.Stats().Evictions)
+
+	m.Set(100, strings.Repeat("x", 20)) // element that exceeds the budget on its own
+	assert.Equal(t, 0, m.Len())
+	assert.Equal(t, 0, m.Weight())
+}
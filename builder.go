@@ -0,0 +1,126 @@
+package hashmap
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Builder constructs a map from many entries in a single pass. It is not safe for concurrent use,
+// the map that Build returns is.
+type Builder[Key hashable, Value any] struct {
+	hasher  func(Key) uintptr
+	entries []builderEntry[Key, Value]
+}
+
+type builderEntry[Key hashable, Value any] struct {
+	hash  uintptr
+	order int // position of the entry in the order of additions
+	key   Key
+	value Value
+}
+
+// NewBuilder returns a new builder that expects the given number of entries.
+func NewBuilder[Key hashable, Value any](sizeHint int) *Builder[Key, Value] {
+	return &Builder[Key, Value]{
+		entries: make([]builderEntry[Key, Value], 0, sizeHint),
+	}
+}
+
+// SetHasher sets a custom hasher for the map that gets built.
+func (b *Builder[Key, Value]) SetHasher(hasher func(Key) uintptr) {
+	b.hasher = hasher
+}
+
+// Len returns the number of entries that were added, including duplicate keys.
+func (b *Builder[Key, Value]) Len() int {
+	return len(b.entries)
+}
+
+// Add adds an entry. If a key is added multiple times, the last value is used.
+func (b *Builder[Key, Value]) Add(key Key, value Value) {
+	b.entries = append(b.entries, builderEntry[Key, Value]{
+		order: len(b.entries),
+		key:   key,
+		value: value,
+	})
+}
+
+// Build returns a map that contains all added entries and resets the builder.
+// The list elements and values of the map are allocated in single blocks of memory, which are
+// only released once all elements that were built were removed from the map.
+func (b *Builder[Key, Value]) Build() *Map[Key, Value] {
+	m := &Map[Key, Value]{}
+	if b.hasher == nil {
+		m.setDefaultHasher()
+	} else {
+		m.SetHasher(b.hasher)
+	}
+
+	entries := b.entries
+	b.entries = nil
+	for i := range entries {
+		entries[i].hash = m.hasher(entries[i].key)
+	}
+	// sorting equal hashes by insertion order lets the last value of duplicate keys win
+	slices.SortFunc(entries, func(a, b builderEntry[Key, Value]) int {
+		if c := cmp.Compare(a.hash, b.hash); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.order, b.order)
+	})
+	entries = deduplicateEntries(entries)
+
+	elements := make([]ListElement[Key, Value], len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		element := &elements[i]
+		element.keyHash = entry.hash
+		element.key = entry.key
+		element.value.Store(&entry.value)
+		if i+1 < len(elements) {
+			element.next.Store(&elements[i+1])
+		}
+	}
+
+	m.linkedList = NewList[Key, Value]()
+	if len(elements) > 0 {
+		m.linkedList.head.next.Store(&elements[0])
+	}
+	m.linkedList.count.Store(uintptr(len(elements)))
+
+	store := makeStore[Key, Value](indexSizeFor(len(elements)))
+	var filled uintptr
+	for i := range elements {
+		index := elements[i].keyHash >> store.keyShifts
+		if store.index[index] == nil { // the first element has the smallest hash of an index
+			store.index[index] = &elements[i]
+			filled++
+		}
+	}
+	store.count.Store(filled)
+	m.store.Store(store)
+	return m
+}
+
+// deduplicateEntries removes all but the last entry of every key from entries sorted by hash.
+func deduplicateEntries[Key hashable, Value any](entries []builderEntry[Key, Value]) []builderEntry[Key, Value] {
+	result := entries[:0]
+	for i := range entries {
+		entry := entries[i]
+		duplicate := false
+		// duplicate keys are adjacent or only separated by other keys with the same hash
+		for j := len(result) - 1; j >= 0 && result[j].hash == entry.hash; j-- {
+			if result[j].key == entry.key {
+				result[j].value = entry.value
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, entry)
+		}
+	}
+
+	clear(entries[len(result):]) // release references to the values of removed duplicates
+	return result[:len(result):len(result)]
+}
@@ -0,0 +1,99 @@
+package hashmap
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+	const count = 1000
+	b := NewBuilder[int, string](count)
+	for i := range count {
+		b.Add(i, "old")
+	}
+	for i := range count {
+		b.Add(i, strconv.Itoa(i)) // duplicates replace the earlier values
+	}
+	assert.Equal(t, 2*count, b.Len())
+
+	m := b.Build()
+	assert.Equal(t, 0, b.Len())
+	assert.Equal(t, count, m.Len())
+
+	for i := range count {
+		value, ok := m.Get(i)
+		assert.True(t, ok)
+		assert.Equal(t, strconv.Itoa(i), value)
+	}
+
+	var previous uintptr
+	items := 0
+	for item := m.linkedList.First(); item != nil; item = item.Next() {
+		assert.True(t, item.keyHash >= previous)
+		previous = item.keyHash
+		items++
+	}
+	assert.Equal(t, count, items)
+}
+
+func TestBuilderConcurrentUse(t *testing.T) {
+	t.Parallel()
+	b := NewBuilder[int, int](0)
+	for i := range 100 {
+		b.Add(i, i)
+	}
+	m := b.Build()
+
+	var wg sync.WaitGroup
+	for g := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				key := 100 + g*1000 + i
+				m.Set(key, key)
+				m.Del(i % 100)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 4000, m.Len())
+	value, ok := m.Get(2500)
+	assert.True(t, ok)
+	assert.Equal(t, 2500, value)
+}
+
+func TestBuilderHashCollision(t *testing.T) {
+	t.Parallel()
+	b := NewBuilder[string, int](0)
+	b.SetHasher(func(string) uintptr { return 4 })
+	b.Add("a", 1)
+	b.Add("b", 2)
+	b.Add("a", 3)
+
+	m := b.Build()
+	assert.Equal(t, 2, m.Len())
+	value, _ := m.Get("a")
+	assert.Equal(t, 3, value)
+	value, _ = m.Get("b")
+	assert.Equal(t, 2, value)
+
+	m.Set("c", 4)
+	value, _ = m.Get("c")
+	assert.Equal(t, 4, value)
+}
+
+func TestBuilderEmpty(t *testing.T) {
+	t.Parallel()
+	m := NewBuilder[int, int](0).Build()
+	assert.Equal(t, 0, m.Len())
+	m.Set(1, 1)
+	value, ok := m.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+}
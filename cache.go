@@ -0,0 +1,326 @@
+package hashmap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRefreshQueueSize is the default size of the queue of keys waiting for a background refresh.
+const defaultRefreshQueueSize = 1024
+
+// cacheLockStripes is the number of mutexes that serialize the changes of a Cache per key,
+// it has to be a power of 2.
+const cacheLockStripes = 64
+
+// Loader loads the value for a key from a backing source.
+// The returned bool reports whether the key exists in the source.
+type Loader[Key hashable, Value any] func(key Key) (Value, bool, error)
+
+// CacheOptions configures a Cache.
+type CacheOptions[Key hashable, Value any] struct {
+	// Loader loads values for keys that are not cached or whose cached value expired.
+	Loader Loader[Key, Value]
+
+	// SoftTTL is the age after which an entry gets refreshed in the background,
+	// readers keep getting the stale value until the refresh finished.
+	// A value of 0 disables refresh-ahead.
+	SoftTTL time.Duration
+
+	// HardTTL is the age after which an entry is not returned anymore and
+	// Get blocks until the value has been reloaded.
+	// A value of 0 disables the hard expiration.
+	HardTTL time.Duration
+
+	// NegativeTTL is the duration for which a key that does not exist in the backing source
+	// is remembered as absent, Get answers these keys without calling the loader.
+	// Absent keys are not counted by Len and not returned by Range, expired ones get
+	// removed in the background in the interval of NegativeTTL.
+	// A value of 0 disables negative caching.
+	NegativeTTL time.Duration
+
+	// RefreshWorkers is the number of goroutines that refresh entries in the background.
+	// Defaults to 1 if SoftTTL is set.
+	RefreshWorkers int
+
+	// RefreshQueueSize is the maximum number of keys waiting for a refresh.
+	// Refreshes are skipped while the queue is full and retried on a later access.
+	RefreshQueueSize int
+
+	// OnRefreshError gets called with the error of a failed background refresh.
+	OnRefreshError func(key Key, err error)
+}
+
+// Cache is a map that loads missing entries using a Loader and refreshes
+// entries in the background once they pass a soft TTL.
+type Cache[Key hashable, Value any] struct {
+	m       *Map[Key, *cacheEntry[Value]]
+	absent  *Map[Key, int64] // keys known to be absent from the source, mapped to their load time
+	options CacheOptions[Key, Value]
+	now     func() int64 // returns the current time in nanoseconds, see nanotime
+
+	// keyLocks serialize the changes of a key, so that a refresh can check and replace
+	// the entry that scheduled it without undoing concurrent changes
+	keyLocks [cacheLockStripes]sync.Mutex
+
+	refreshes chan refreshRequest[Key, Value]
+	done      chan struct{}
+	closeOnce sync.Once
+	workers   sync.WaitGroup
+}
+
+// cacheEntry is the value that a Cache stores in its map for every key.
+// Entries are immutable apart from the refreshing flag, a refresh replaces the entry.
+type cacheEntry[Value any] struct {
+	value  Value
+	loaded int64 // load time in nanoseconds
+
+	// refreshing marks a background refresh of this entry as queued or in progress
+	// this is using uintptr instead of atomic.Bool to avoid using 32 bit int on 64 bit systems
+	refreshing atomic.Uintptr
+}
+
+// refreshRequest is a queued background refresh of the entry that scheduled it.
+type refreshRequest[Key hashable, Value any] struct {
+	key   Key
+	entry *cacheEntry[Value]
+}
+
+// NewCache returns a new cache instance that uses the given options.
+// The background refresh workers are stopped by calling Close.
+func NewCache[Key hashable, Value any](options CacheOptions[Key, Value]) *Cache[Key, Value] {
+	if options.Loader == nil {
+		panic("hashmap: cache loader is not set")
+	}
+
+	c := &Cache[Key, Value]{
+		m:       New[Key, *cacheEntry[Value]](),
+		absent:  New[Key, int64](),
+		options: options,
+		now:     nanotime,
+		done:    make(chan struct{}),
+	}
+
+	if options.SoftTTL > 0 {
+		workers := max(options.RefreshWorkers, 1)
+		queueSize := options.RefreshQueueSize
+		if queueSize <= 0 {
+			queueSize = defaultRefreshQueueSize
+		}
+
+		c.refreshes = make(chan refreshRequest[Key, Value], queueSize)
+		c.workers.Add(workers)
+		for range workers {
+			go c.refreshWorker()
+		}
+	}
+	if options.NegativeTTL > 0 {
+		c.workers.Add(1)
+		go c.absentSweeper()
+	}
+	return c
+}
+
+// Close stops the background refresh and sweep workers and waits for running refreshes to finish.
+func (c *Cache[Key, Value]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	c.workers.Wait()
+}
+
+// Len returns the number of entries within the cache.
+func (c *Cache[Key, Value]) Len() int {
+	return c.m.Len()
+}
+
+// Get returns the value for the key. Entries that are missing or passed the hard TTL
+// are loaded synchronously, entries that passed the soft TTL are returned and get
+// refreshed in the background.
+// The returned bool is false if the key does not exist in the backing source.
+func (c *Cache[Key, Value]) Get(key Key) (Value, bool, error) {
+	entry, ok := c.m.Get(key)
+	if ok {
+		age := time.Duration(c.now() - entry.loaded)
+		if c.options.HardTTL == 0 || age < c.options.HardTTL {
+			if c.options.SoftTTL > 0 && age >= c.options.SoftTTL {
+				c.scheduleRefresh(key, entry)
+			}
+			return entry.value, true, nil
+		}
+	} else if c.isKnownAbsent(key) {
+		return *new(Value), false, nil
+	}
+
+	return c.load(key)
+}
+
+// Set stores the value under the key as freshly loaded.
+func (c *Cache[Key, Value]) Set(key Key, value Value) {
+	lock := c.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.m.Set(key, c.newEntry(value))
+	c.absent.Del(key)
+}
+
+// Del deletes the key from the cache and returns whether the key was deleted.
+// A key that is remembered as absent is forgotten as well.
+func (c *Cache[Key, Value]) Del(key Key) bool {
+	lock := c.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.absent.Del(key)
+	return c.m.Del(key)
+}
+
+// Range calls f sequentially for each key and value present in the cache that did
+// not pass the hard TTL. If f returns false, range stops the iteration.
+func (c *Cache[Key, Value]) Range(f func(Key, Value) bool) {
+	now := c.now()
+	c.m.Range(func(key Key, entry *cacheEntry[Value]) bool {
+		if c.options.HardTTL > 0 && time.Duration(now-entry.loaded) >= c.options.HardTTL {
+			return true
+		}
+		return f(key, entry.value)
+	})
+}
+
+func (c *Cache[Key, Value]) newEntry(value Value) *cacheEntry[Value] {
+	return &cacheEntry[Value]{
+		value:  value,
+		loaded: c.now(),
+	}
+}
+
+// load calls the loader for the key and stores the result.
+func (c *Cache[Key, Value]) load(key Key) (Value, bool, error) {
+	value, found, err := c.options.Loader(key)
+	if err != nil {
+		return *new(Value), false, err
+	}
+
+	lock := c.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !found {
+		c.m.Del(key) // remove an expired entry of a key that does not exist anymore
+		if c.options.NegativeTTL > 0 {
+			c.absent.Set(key, c.now())
+		}
+		return *new(Value), false, nil
+	}
+
+	c.m.Set(key, c.newEntry(value))
+	c.absent.Del(key)
+	return value, true, nil
+}
+
+// isKnownAbsent returns whether the key was recently loaded and did not exist in the source.
+// Expired absent entries get removed.
+func (c *Cache[Key, Value]) isKnownAbsent(key Key) bool {
+	loaded, ok := c.absent.Get(key)
+	if !ok {
+		return false
+	}
+	if time.Duration(c.now()-loaded) < c.options.NegativeTTL {
+		return true
+	}
+	c.absent.Del(key)
+	return false
+}
+
+// sweepAbsent removes all expired absent entries. An entry that gets renewed concurrently
+// may be removed as well, which only costs an additional load of the key.
+func (c *Cache[Key, Value]) sweepAbsent() {
+	now := c.now()
+	c.absent.Range(func(key Key, loaded int64) bool {
+		if time.Duration(now-loaded) >= c.options.NegativeTTL {
+			c.absent.Del(key)
+		}
+		return true
+	})
+}
+
+// absentSweeper removes expired absent entries in the interval of the negative TTL, this
+// bounds the absent entries of keys that are not looked up again.
+func (c *Cache[Key, Value]) absentSweeper() {
+	defer c.workers.Done()
+
+	ticker := time.NewTicker(c.options.NegativeTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.sweepAbsent()
+		}
+	}
+}
+
+// scheduleRefresh queues a background refresh for the key unless one is already pending.
+func (c *Cache[Key, Value]) scheduleRefresh(key Key, entry *cacheEntry[Value]) {
+	if !entry.refreshing.CompareAndSwap(0, 1) {
+		return // refresh is already queued
+	}
+
+	select {
+	case c.refreshes <- refreshRequest[Key, Value]{key: key, entry: entry}:
+	default:
+		entry.refreshing.Store(0) // queue is full, retry on a later access
+	}
+}
+
+func (c *Cache[Key, Value]) refreshWorker() {
+	defer c.workers.Done()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case request := <-c.refreshes:
+			c.refresh(request.key, request.entry)
+		}
+	}
+}
+
+// refresh reloads the key and replaces the entry that scheduled the refresh. The result is
+// discarded if the entry got deleted or replaced in the meantime, a refresh does not undo
+// a concurrent Set, Del or load.
+func (c *Cache[Key, Value]) refresh(key Key, entry *cacheEntry[Value]) {
+	value, found, err := c.options.Loader(key)
+	if err != nil {
+		entry.refreshing.Store(0) // allow the refresh to be retried
+		if c.options.OnRefreshError != nil {
+			c.options.OnRefreshError(key, err)
+		}
+		return
+	}
+
+	lock := c.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if current, ok := c.m.Get(key); !ok || current != entry {
+		return
+	}
+
+	if !found {
+		c.m.Del(key) // the key does not exist anymore
+		if c.options.NegativeTTL > 0 {
+			c.absent.Set(key, c.now())
+		}
+		return
+	}
+	c.m.Set(key, c.newEntry(value))
+}
+
+// keyLock returns the mutex that serializes the changes of the key.
+func (c *Cache[Key, Value]) keyLock(key Key) *sync.Mutex {
+	return &c.keyLocks[c.m.hasher(key)&(cacheLockStripes-1)]
+}
@@ -0,0 +1,263 @@
+package hashmap
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+// waitFor polls the condition until it is true or the test times out.
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition was not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheLoad(t *testing.T) {
+	t.Parallel()
+	var loads atomic.Int64
+	c := NewCache(CacheOptions[int, string]{
+		Loader: func(key int) (string, bool, error) {
+			loads.Add(1)
+			if key > 10 {
+				return "", false, nil
+			}
+			return "value", true, nil
+		},
+	})
+	defer c.Close()
+
+	value, ok, err := c.Get(1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+
+	_, _, _ = c.Get(1)
+	assert.Equal(t, 1, loads.Load())
+	assert.Equal(t, 1, c.Len())
+
+	_, ok, err = c.Get(11)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCacheRefreshAhead(t *testing.T) {
+	t.Parallel()
+	var clock, version atomic.Int64
+	c := NewCache(CacheOptions[int, int64]{
+		Loader: func(_ int) (int64, bool, error) {
+			return version.Load(), true, nil
+		},
+		SoftTTL: time.Minute,
+		HardTTL: time.Hour,
+	})
+	defer c.Close()
+	c.now = clock.Load
+
+	value, _, _ := c.Get(1)
+	assert.Equal(t, 0, value)
+
+	version.Store(1)
+	clock.Store(int64(2 * time.Minute))
+	value, ok, err := c.Get(1) // stale value is returned while refreshing
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0, value)
+
+	waitFor(t, func() bool {
+		value, _, _ = c.Get(1)
+		return value == 1
+	})
+
+	version.Store(2)
+	clock.Store(int64(2 * time.Hour))
+	value, _, _ = c.Get(1) // hard expired value is loaded synchronously
+	assert.Equal(t, 2, value)
+}
+
+func TestCacheRefreshError(t *testing.T) {
+	t.Parallel()
+	var clock atomic.Int64
+	errLoad := errors.New("load failed")
+	failed := make(chan int, 1)
+	c := NewCache(CacheOptions[int, int]{
+		Loader: func(key int) (int, bool, error) {
+			if clock.Load() > 0 {
+				return 0, false, errLoad
+			}
+			return key, true, nil
+		},
+		SoftTTL: time.Minute,
+		OnRefreshError: func(key int, err error) {
+			if errors.Is(err, errLoad) {
+				failed <- key
+			}
+		},
+	})
+	defer c.Close()
+	c.now = clock.Load
+
+	_, _, _ = c.Get(1)
+	clock.Store(int64(2 * time.Minute))
+	value, ok, err := c.Get(1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 1, <-failed)
+}
+
+func TestCacheNegativeCaching(t *testing.T) {
+	t.Parallel()
+	var clock, loads atomic.Int64
+	var exists atomic.Bool
+	c := NewCache(CacheOptions[int, int]{
+		Loader: func(key int) (int, bool, error) {
+			loads.Add(1)
+			return key, exists.Load(), nil
+		},
+		NegativeTTL: time.Minute,
+	})
+	defer c.Close()
+	c.now = clock.Load
+
+	_, ok, err := c.Get(1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, _ = c.Get(1) // answered from the negative cache
+	assert.False(t, ok)
+	assert.Equal(t, 1, loads.Load())
+	assert.Equal(t, 0, c.Len())
+
+	count := 0
+	c.Range(func(int, int) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, 0, count)
+
+	exists.Store(true)
+	clock.Store(int64(2 * time.Minute))
+	value, ok, _ := c.Get(1) // negative entry expired
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 2, loads.Load())
+	assert.Equal(t, 1, c.Len())
+
+	c.Set(2, 2)
+	value, ok, _ = c.Get(2)
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, 2, loads.Load())
+}
+
+func TestCacheSweepAbsent(t *testing.T) {
+	t.Parallel()
+	var clock atomic.Int64
+	c := NewCache(CacheOptions[int, int]{
+		Loader: func(key int) (int, bool, error) {
+			return key, false, nil
+		},
+		NegativeTTL: time.Minute,
+	})
+	defer c.Close()
+	c.now = clock.Load
+
+	for i := range 10 {
+		_, _, _ = c.Get(i)
+	}
+	assert.Equal(t, 10, c.absent.Len())
+
+	clock.Store(int64(30 * time.Second))
+	_, _, _ = c.Get(10)
+	clock.Store(int64(time.Minute))
+	c.sweepAbsent() // only the absent entry of the key loaded last is still valid
+	assert.Equal(t, 1, c.absent.Len())
+
+	_, ok := c.absent.Get(10)
+	assert.True(t, ok)
+}
+
+func TestCacheRefreshConcurrentChange(t *testing.T) {
+	t.Parallel()
+	var clock atomic.Int64
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	c := NewCache(CacheOptions[int, int]{
+		Loader: func(key int) (int, bool, error) {
+			if clock.Load() > 0 {
+				loading <- struct{}{}
+				<-release
+			}
+			return key, true, nil
+		},
+		SoftTTL:        time.Minute,
+		RefreshWorkers: 1,
+	})
+	defer c.Close()
+	c.now = clock.Load
+
+	_, _, _ = c.Get(1)
+	_, _, _ = c.Get(2)
+	clock.Store(int64(2 * time.Minute))
+
+	_, _, _ = c.Get(1)
+	<-loading
+	c.Set(1, 10) // the running refresh must not overwrite the new value
+	release <- struct{}{}
+
+	_, _, _ = c.Get(2)
+	<-loading // the single worker finished the refresh of key 1
+	c.Del(2)  // the running refresh must not restore the deleted key
+	release <- struct{}{}
+	c.Close() // wait for the refresh of key 2 to finish
+
+	value, ok, err := c.Get(1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 10, value)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCacheRefreshAbsentConcurrentSet(t *testing.T) {
+	t.Parallel()
+	loading := make(chan struct{})
+	c := NewCache(CacheOptions[int, int]{
+		Loader: func(int) (int, bool, error) {
+			loading <- struct{}{}
+			return 0, false, nil // the key got deleted from the source
+		},
+		NegativeTTL: time.Minute,
+	})
+	defer c.Close()
+
+	c.Set(1, 1)
+	entry, _ := c.m.Get(1)
+
+	lock := c.keyLock(1)
+	lock.Lock()
+	refreshed := make(chan struct{})
+	go func() {
+		c.refresh(1, entry)
+		close(refreshed)
+	}()
+	<-loading
+	c.m.Set(1, c.newEntry(2)) // a set that lands between the load and the removal of the refresh
+	lock.Unlock()
+	<-refreshed
+
+	value, ok, err := c.Get(1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+	_, ok = c.absent.Get(1)
+	assert.False(t, ok)
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/cornelk/hashmap"
+)
+
+// newFlagSet returns a flag set for a command that returns parsing errors instead of exiting.
+func newFlagSet(command string) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// parseArgs parses the flags and verifies the number of remaining arguments.
+func parseArgs(flags *flag.FlagSet, args []string, count int) ([]string, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("%s: %w", flags.Name(), err)
+	}
+	if flags.NArg() != count {
+		return nil, fmt.Errorf("%s: expected %d arguments, got %d\n%w", flags.Name(), count, flags.NArg(), errUsage)
+	}
+	return flags.Args(), nil
+}
+
+func inspect(args []string, stdout io.Writer) error {
+	files, err := parseArgs(newFlagSet("inspect"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	var stats struct {
+		entries, deleted       int
+		valueBytes             int
+		minValue, maxValue     int
+		minVersion, maxVersion uint64
+	}
+	info, size, err := readSnapshot(files[0], func(entry hashmap.SnapshotEntry) error {
+		if stats.entries == 0 {
+			stats.minValue, stats.minVersion = len(entry.Value), entry.Version
+		}
+		stats.entries++
+		if entry.Deleted {
+			stats.deleted++
+		}
+		stats.valueBytes += len(entry.Value)
+		stats.minValue = min(stats.minValue, len(entry.Value))
+		stats.maxValue = max(stats.maxValue, len(entry.Value))
+		stats.minVersion = min(stats.minVersion, entry.Version)
+		stats.maxVersion = max(stats.maxVersion, entry.Version)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	hasher := "custom"
+	if info.DefaultHasher {
+		hasher = "default"
+	}
+	fmt.Fprintf(stdout, "file:           %s\n", files[0])
+	fmt.Fprintf(stdout, "size:           %d bytes\n", size)
+	fmt.Fprintf(stdout, "format version: %d\n", info.Version)
+	fmt.Fprintf(stdout, "key kind:       %s\n", info.KeyKind)
+	fmt.Fprintf(stdout, "hasher:         %s\n", hasher)
+	fmt.Fprintf(stdout, "header count:   %d\n", info.Count)
+	if info.Version > 1 {
+		fmt.Fprintf(stdout, "since version:  %d\n", info.Since)
+		fmt.Fprintf(stdout, "map version:    %d\n", info.MapVersion)
+	}
+	fmt.Fprintf(stdout, "entries:        %d\n", stats.entries)
+	fmt.Fprintf(stdout, "deleted:        %d\n", stats.deleted)
+	fmt.Fprintf(stdout, "value bytes:    %d (min %d, max %d)\n", stats.valueBytes, stats.minValue, stats.maxValue)
+	if info.Version > 1 && stats.entries > 0 {
+		fmt.Fprintf(stdout, "entry versions: %d - %d\n", stats.minVersion, stats.maxVersion)
+	}
+	return nil
+}
+
+// dumpRecord is the JSON representation of an entry.
+type dumpRecord struct {
+	Hash    string `json:"hash"`
+	Key     any    `json:"key"`
+	Value   any    `json:"value,omitempty"`
+	Version uint64 `json:"version,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+func dump(args []string, stdout io.Writer) error {
+	flags := newFlagSet("dump")
+	format := flags.String("format", "jsonl", "output format: jsonl or csv")
+	values := flags.String("values", valuesHex, "value decoding: hex, string, int, uint or float")
+	files, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	decode, err := valueDecoder(*values)
+	if err != nil {
+		return err
+	}
+
+	var write func(hashmap.SnapshotEntry) error
+	switch *format {
+	case "jsonl":
+		encoder := json.NewEncoder(stdout)
+		write = func(entry hashmap.SnapshotEntry) error {
+			record := dumpRecord{
+				Hash:    formatHash(entry.Hash),
+				Key:     entry.Key,
+				Version: entry.Version,
+				Deleted: entry.Deleted,
+			}
+			if !entry.Deleted {
+				if record.Value, err = decode(entry.Value); err != nil {
+					return fmt.Errorf("key %v: %w", entry.Key, err)
+				}
+			}
+			return encoder.Encode(record)
+		}
+
+	case "csv":
+		writer := csv.NewWriter(stdout)
+		defer writer.Flush()
+		if err = writer.Write([]string{"hash", "key", "value", "version", "deleted"}); err != nil {
+			return err
+		}
+		write = func(entry hashmap.SnapshotEntry) error {
+			value := ""
+			if !entry.Deleted {
+				decoded, err := decode(entry.Value)
+				if err != nil {
+					return fmt.Errorf("key %v: %w", entry.Key, err)
+				}
+				value = fmt.Sprint(decoded)
+			}
+			return writer.Write([]string{
+				formatHash(entry.Hash),
+				fmt.Sprint(entry.Key),
+				value,
+				strconv.FormatUint(entry.Version, 10),
+				strconv.FormatBool(entry.Deleted),
+			})
+		}
+
+	default:
+		return fmt.Errorf("dump: %w %q", errUnsupported, *format)
+	}
+
+	_, _, err = readSnapshot(files[0], write)
+	return err
+}
+
+// errKeyNotFound is returned by the lookup command if the key does not exist.
+var errKeyNotFound = errors.New("key not found")
+
+func lookup(args []string, stdout io.Writer) error {
+	flags := newFlagSet("lookup")
+	values := flags.String("values", valuesHex, "value decoding: hex, string, int, uint or float")
+	files, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	decode, err := valueDecoder(*values)
+	if err != nil {
+		return err
+	}
+
+	var found *hashmap.SnapshotEntry
+	var key any
+	_, _, err = readSnapshot(files[0], func(entry hashmap.SnapshotEntry) error {
+		if key == nil {
+			// the key can only be parsed once the key kind of the snapshot is known
+			if key, err = parseKey(files[1], entry.Key); err != nil {
+				return err
+			}
+		}
+		if entry.Key == key {
+			found = &entry
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if found == nil {
+		return fmt.Errorf("%w: %s", errKeyNotFound, files[1])
+	}
+
+	if found.Deleted {
+		fmt.Fprintf(stdout, "key %v: deleted at version %d\n", found.Key, found.Version)
+		return nil
+	}
+	value, err := decode(found.Value)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "hash:    %s\n", formatHash(found.Hash))
+	if found.Version > 0 {
+		fmt.Fprintf(stdout, "version: %d\n", found.Version)
+	}
+	fmt.Fprintf(stdout, "value:   %v\n", value)
+	return nil
+}
+
+func diff(args []string, stdout io.Writer) error {
+	flags := newFlagSet("diff")
+	values := flags.String("values", valuesHex, "value decoding: hex, string, int, uint or float")
+	files, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	decode, err := valueDecoder(*values)
+	if err != nil {
+		return err
+	}
+
+	old, err := readState(files[0])
+	if err != nil {
+		return err
+	}
+	current, err := readState(files[1])
+	if err != nil {
+		return err
+	}
+
+	format := func(entry hashmap.SnapshotEntry) string {
+		if entry.Deleted {
+			return "<deleted>"
+		}
+		value, err := decode(entry.Value)
+		if err != nil {
+			return fmt.Sprintf("<%v>", err)
+		}
+		return fmt.Sprint(value)
+	}
+
+	changes := 0
+	for _, entry := range current.entries {
+		previous, ok := old.lookup(entry.Key)
+		switch {
+		case !ok:
+			fmt.Fprintf(stdout, "+ %v: %s\n", entry.Key, format(entry))
+		case string(previous.Value) != string(entry.Value) || previous.Deleted != entry.Deleted:
+			fmt.Fprintf(stdout, "~ %v: %s -> %s\n", entry.Key, format(previous), format(entry))
+		default:
+			continue
+		}
+		changes++
+	}
+	for _, entry := range old.entries {
+		if _, ok := current.lookup(entry.Key); !ok {
+			fmt.Fprintf(stdout, "- %v: %s\n", entry.Key, format(entry))
+			changes++
+		}
+	}
+
+	if changes > 0 {
+		return errDifferent
+	}
+	return nil
+}
+
+func verify(args []string, stdout io.Writer) error {
+	files, err := parseArgs(newFlagSet("verify"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	entries := 0
+	_, size, err := readSnapshot(files[0], func(hashmap.SnapshotEntry) error {
+		entries++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", files[0], err)
+	}
+	fmt.Fprintf(stdout, "%s: ok, %d entries, %d bytes\n", files[0], entries, size)
+	return nil
+}
+
+func convert(args []string, stdout io.Writer) error {
+	flags := newFlagSet("convert")
+	version := flags.Int("version", 0, "format version of the output file: 1 or 2")
+	files, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	if *version != 1 && *version != 2 {
+		return fmt.Errorf("convert: %w format version %d", errUnsupported, *version)
+	}
+
+	input, err := os.Open(files[0])
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	reader, err := hashmap.NewSnapshotReader(input)
+	if err != nil {
+		return err
+	}
+	info := reader.Info()
+	if *version == 1 && info.Since > 0 {
+		return fmt.Errorf("convert: %w: delta snapshot since version %d to a full snapshot", errUnsupported, info.Since)
+	}
+	info.Version = *version
+
+	output, err := os.Create(files[1])
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	writer, err := hashmap.NewSnapshotWriter(output, info)
+	if err != nil {
+		return err
+	}
+	entries := 0
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = writer.Write(entry); err != nil {
+			return err
+		}
+		entries++
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	if err = output.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "converted %d entries from format version %d to %d\n", entries, reader.Info().Version, *version)
+	return nil
+}
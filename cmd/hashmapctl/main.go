@@ -0,0 +1,61 @@
+// Package main implements hashmapctl, a tool to inspect snapshot files that were written
+// by the WriteTo and WriteDelta methods of a hashmap.
+//
+// Usage:
+//
+//	hashmapctl inspect <file>
+//	hashmapctl dump [-format jsonl|csv] [-values hex|string|int|uint|float] <file>
+//	hashmapctl lookup [-values ...] <file> <key>
+//	hashmapctl diff [-values ...] <old file> <new file>
+//	hashmapctl verify <file>
+//	hashmapctl convert -version 1|2 <input file> <output file>
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var errUsage = errors.New("usage: hashmapctl inspect|dump|lookup|diff|verify|convert [flags] <file>...")
+
+// errDifferent is returned by the diff command if the snapshots differ.
+var errDifferent = errors.New("snapshots differ")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	switch {
+	case err == nil:
+	case errors.Is(err, errDifferent):
+		os.Exit(1)
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+// run executes the command of the arguments and writes its output to stdout.
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "inspect":
+		return inspect(args, stdout)
+	case "dump":
+		return dump(args, stdout)
+	case "lookup":
+		return lookup(args, stdout)
+	case "diff":
+		return diff(args, stdout)
+	case "verify":
+		return verify(args, stdout)
+	case "convert":
+		return convert(args, stdout)
+	default:
+		return fmt.Errorf("unknown command %q\n%w", command, errUsage)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cornelk/hashmap"
+	"github.com/cornelk/hashmap/assert"
+)
+
+var update = flag.Bool("update", false, "update the snapshot files and golden files in testdata")
+
+// writeTestSnapshots writes the snapshot files that the tests use as input.
+func writeTestSnapshots(t *testing.T) {
+	t.Helper()
+	m := hashmap.New[int, string]()
+	m.SetTombstoneRetention(time.Hour)
+	for i := 1; i <= 5; i++ {
+		m.Set(i, "value-"+string(rune('0'+i)))
+	}
+	writeTestFile(t, "old.snapshot", func(buf *bytes.Buffer) error {
+		_, err := m.WriteTo(buf)
+		return err
+	})
+
+	version := m.Version()
+	m.Set(2, "changed")
+	m.Del(4)
+	m.Set(6, "value-6")
+	writeTestFile(t, "new.snapshot", func(buf *bytes.Buffer) error {
+		_, err := m.WriteTo(buf)
+		return err
+	})
+	writeTestFile(t, "delta.snapshot", func(buf *bytes.Buffer) error {
+		_, err := m.WriteDelta(buf, version)
+		return err
+	})
+}
+
+func writeTestFile(t *testing.T, name string, write func(*bytes.Buffer) error) {
+	t.Helper()
+	var buf bytes.Buffer
+	assert.NoError(t, write(&buf))
+	assert.NoError(t, os.WriteFile(filepath.Join("testdata", name), buf.Bytes(), 0o644))
+}
+
+func TestCommands(t *testing.T) {
+	if *update {
+		writeTestSnapshots(t)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		err  error
+	}{
+		{name: "inspect", args: []string{"inspect", "testdata/old.snapshot"}},
+		{name: "inspect_delta", args: []string{"inspect", "testdata/delta.snapshot"}},
+		{name: "dump_jsonl", args: []string{"dump", "-values", "string", "testdata/new.snapshot"}},
+		{name: "dump_csv", args: []string{"dump", "-format", "csv", "-values", "string", "testdata/delta.snapshot"}},
+		{name: "dump_hex", args: []string{"dump", "testdata/old.snapshot"}},
+		{name: "lookup", args: []string{"lookup", "-values", "string", "testdata/new.snapshot", "2"}},
+		{name: "lookup_deleted", args: []string{"lookup", "testdata/delta.snapshot", "4"}},
+		{name: "diff", args: []string{"diff", "-values", "string", "testdata/old.snapshot", "testdata/new.snapshot"}, err: errDifferent},
+		{name: "verify", args: []string{"verify", "testdata/new.snapshot"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := run(test.args, &stdout)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			golden := filepath.Join("testdata", test.name+".golden")
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, stdout.Bytes(), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), stdout.String())
+		})
+	}
+}
+
+func TestLookupNotFound(t *testing.T) {
+	t.Parallel()
+	err := run([]string{"lookup", "testdata/old.snapshot", "100"}, &bytes.Buffer{})
+	assert.ErrorIs(t, err, errKeyNotFound)
+}
+
+func TestDiffEqual(t *testing.T) {
+	t.Parallel()
+	var stdout bytes.Buffer
+	err := run([]string{"diff", "testdata/old.snapshot", "testdata/old.snapshot"}, &stdout)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stdout.Len())
+}
+
+func TestVerifyCorrupted(t *testing.T) {
+	t.Parallel()
+	data, err := os.ReadFile("testdata/old.snapshot")
+	assert.NoError(t, err)
+	data[len(data)-10] ^= 0xff
+
+	path := filepath.Join(t.TempDir(), "corrupted.snapshot")
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+	err = run([]string{"verify", path}, &bytes.Buffer{})
+	assert.ErrorIs(t, err, hashmap.ErrChecksumMismatch)
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	v2 := filepath.Join(dir, "v2.snapshot")
+	v1 := filepath.Join(dir, "v1.snapshot")
+
+	assert.NoError(t, run([]string{"convert", "-version", "2", "testdata/old.snapshot", v2}, &bytes.Buffer{}))
+	assert.NoError(t, run([]string{"convert", "-version", "1", v2, v1}, &bytes.Buffer{}))
+
+	expected, err := os.ReadFile("testdata/old.snapshot")
+	assert.NoError(t, err)
+	converted, err := os.ReadFile(v1)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, converted)
+
+	m := hashmap.New[int, string]()
+	file, err := os.Open(v2)
+	assert.NoError(t, err)
+	defer file.Close()
+	_, err = m.ReadFrom(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, m.Len())
+
+	err = run([]string{"convert", "-version", "1", "testdata/delta.snapshot", v1}, &bytes.Buffer{})
+	assert.True(t, err != nil)
+}
+
+func TestUsage(t *testing.T) {
+	t.Parallel()
+	assert.ErrorIs(t, run(nil, &bytes.Buffer{}), errUsage)
+	assert.ErrorIs(t, run([]string{"unknown"}, &bytes.Buffer{}), errUsage)
+	assert.ErrorIs(t, run([]string{"inspect"}, &bytes.Buffer{}), errUsage)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/cornelk/hashmap"
+)
+
+const (
+	valuesHex    = "hex"
+	valuesString = "string"
+	valuesInt    = "int"
+	valuesUint   = "uint"
+	valuesFloat  = "float"
+)
+
+var (
+	errUnsupported    = errors.New("unsupported")
+	errMalformedValue = errors.New("malformed value")
+)
+
+// readSnapshot calls f for every entry of the snapshot file and returns the snapshot
+// info and the number of bytes read.
+func readSnapshot(path string, f func(hashmap.SnapshotEntry) error) (hashmap.SnapshotInfo, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return hashmap.SnapshotInfo{}, 0, err
+	}
+	defer file.Close()
+
+	reader, err := hashmap.NewSnapshotReader(file)
+	if err != nil {
+		return hashmap.SnapshotInfo{}, 0, err
+	}
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return reader.Info(), reader.BytesRead(), nil
+		}
+		if err != nil {
+			return reader.Info(), reader.BytesRead(), err
+		}
+		if err = f(entry); err != nil {
+			return reader.Info(), reader.BytesRead(), err
+		}
+	}
+}
+
+// state is the content of a snapshot file with a single entry per key.
+type state struct {
+	entries []hashmap.SnapshotEntry // in file order
+	keys    map[any]int             // index of the entry of the key
+}
+
+// readState reads all entries of a snapshot file, later entries of a key replace earlier ones.
+func readState(path string) (*state, error) {
+	s := &state{keys: map[any]int{}}
+	_, _, err := readSnapshot(path, func(entry hashmap.SnapshotEntry) error {
+		if i, ok := s.keys[entry.Key]; ok {
+			s.entries[i] = entry
+			return nil
+		}
+		s.keys[entry.Key] = len(s.entries)
+		s.entries = append(s.entries, entry)
+		return nil
+	})
+	return s, err
+}
+
+func (s *state) lookup(key any) (hashmap.SnapshotEntry, bool) {
+	i, ok := s.keys[key]
+	if !ok {
+		return hashmap.SnapshotEntry{}, false
+	}
+	return s.entries[i], true
+}
+
+// formatHash returns the hash as fixed width hex string.
+func formatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// parseKey parses the key argument into the type of the example key.
+func parseKey(s string, example any) (any, error) {
+	var key any
+	var err error
+
+	switch example.(type) {
+	case string:
+		return s, nil
+	case int8:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, 8)
+		key = int8(v)
+	case int16:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, 16)
+		key = int16(v)
+	case int32:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, 32)
+		key = int32(v)
+	case int64:
+		key, err = strconv.ParseInt(s, 10, 64)
+	case uint8:
+		var v uint64
+		v, err = strconv.ParseUint(s, 10, 8)
+		key = uint8(v)
+	case uint16:
+		var v uint64
+		v, err = strconv.ParseUint(s, 10, 16)
+		key = uint16(v)
+	case uint32:
+		var v uint64
+		v, err = strconv.ParseUint(s, 10, 32)
+		key = uint32(v)
+	case uint64:
+		key, err = strconv.ParseUint(s, 10, 64)
+	case float32:
+		var v float64
+		v, err = strconv.ParseFloat(s, 32)
+		key = float32(v)
+	case float64:
+		key, err = strconv.ParseFloat(s, 64)
+	default:
+		return nil, fmt.Errorf("%w key type %T", errUnsupported, example)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("parsing key %q as %T: %w", s, example, err)
+	}
+	return key, nil
+}
+
+// valueDecoder returns a function that decodes values that were encoded by the default
+// value codec of the map. Numbers are decoded based on the length of the encoding.
+func valueDecoder(mode string) (func([]byte) (any, error), error) {
+	switch mode {
+	case valuesHex:
+		return func(data []byte) (any, error) {
+			return hex.EncodeToString(data), nil
+		}, nil
+
+	case valuesString:
+		return func(data []byte) (any, error) {
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) != length {
+				return nil, fmt.Errorf("%w string", errMalformedValue)
+			}
+			return string(data[n:]), nil
+		}, nil
+
+	case valuesInt:
+		return func(data []byte) (any, error) {
+			switch len(data) {
+			case 1:
+				return int8(data[0]), nil
+			case 2:
+				return int16(binary.LittleEndian.Uint16(data)), nil
+			case 4:
+				return int32(binary.LittleEndian.Uint32(data)), nil
+			case 8:
+				return int64(binary.LittleEndian.Uint64(data)), nil
+			default:
+				return nil, fmt.Errorf("%w integer of %d bytes", errMalformedValue, len(data))
+			}
+		}, nil
+
+	case valuesUint:
+		return func(data []byte) (any, error) {
+			switch len(data) {
+			case 1:
+				return data[0], nil
+			case 2:
+				return binary.LittleEndian.Uint16(data), nil
+			case 4:
+				return binary.LittleEndian.Uint32(data), nil
+			case 8:
+				return binary.LittleEndian.Uint64(data), nil
+			default:
+				return nil, fmt.Errorf("%w integer of %d bytes", errMalformedValue, len(data))
+			}
+		}, nil
+
+	case valuesFloat:
+		return func(data []byte) (any, error) {
+			switch len(data) {
+			case 4:
+				return math.Float32frombits(binary.LittleEndian.Uint32(data)), nil
+			case 8:
+				return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+			default:
+				return nil, fmt.Errorf("%w float of %d bytes", errMalformedValue, len(data))
+			}
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("%w value decoding %q", errUnsupported, mode)
+	}
+}
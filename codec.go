@@ -0,0 +1,212 @@
+package hashmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+var errShortBuffer = errors.New("unexpected end of data")
+
+// ValueCodec encodes and decodes values for the binary serialization of a map.
+type ValueCodec[Value any] interface {
+	// AppendValue appends the encoded value to the buffer and returns the extended buffer.
+	AppendValue(buf []byte, value Value) ([]byte, error)
+	// DecodeValue decodes a value that was encoded by AppendValue.
+	DecodeValue(data []byte) (Value, error)
+}
+
+// GobCodec is a ValueCodec that encodes every value independently using encoding/gob.
+type GobCodec[Value any] struct{}
+
+// AppendValue appends the gob encoded value to the buffer.
+func (GobCodec[Value]) AppendValue(buf []byte, value Value) ([]byte, error) {
+	b := bytes.NewBuffer(buf)
+	if err := gob.NewEncoder(b).Encode(&value); err != nil {
+		return buf, fmt.Errorf("gob encoding value: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// DecodeValue decodes a gob encoded value.
+func (GobCodec[Value]) DecodeValue(data []byte) (Value, error) {
+	var value Value
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return value, fmt.Errorf("gob decoding value: %w", err)
+	}
+	return value, nil
+}
+
+// SetValueCodec sets the codec that is used to encode values for the binary serialization.
+// By default numeric and string values use a compact fixed encoding and all other types use gob.
+func (m *Map[Key, Value]) SetValueCodec(codec ValueCodec[Value]) {
+	m.valueCodec = codec
+}
+
+// getValueCodec returns the value codec that was set or the default codec for the value type.
+func (m *Map[Key, Value]) getValueCodec() ValueCodec[Value] {
+	if m.valueCodec != nil {
+		return m.valueCodec
+	}
+	return defaultValueCodec[Value]()
+}
+
+// defaultValueCodec returns the default codec for the value type.
+func defaultValueCodec[Value any]() ValueCodec[Value] {
+	if codec, ok := newPrimitiveCodec[Value](); ok {
+		return codec
+	}
+	return GobCodec[Value]{}
+}
+
+// primitiveCodec encodes numeric types in little endian byte order with a fixed width
+// and strings with a length prefix. int, uint and uintptr are always encoded as 64 bit
+// values to be portable between platforms.
+type primitiveCodec[T any] struct {
+	kind  reflect.Kind
+	size  uintptr // size of the type in memory
+	width int     // size of the encoding, 0 for strings
+}
+
+// newPrimitiveCodec returns a codec for T if the kind of T is supported.
+func newPrimitiveCodec[T any]() (primitiveCodec[T], bool) {
+	typ := reflect.TypeFor[T]()
+	codec := primitiveCodec[T]{
+		kind: typ.Kind(),
+		size: typ.Size(),
+	}
+
+	switch codec.kind {
+	case reflect.Int, reflect.Uint, reflect.Uintptr:
+		codec.width = 8
+	case reflect.Int8, reflect.Uint8, reflect.Int16, reflect.Uint16, reflect.Int32, reflect.Uint32,
+		reflect.Int64, reflect.Uint64, reflect.Float32, reflect.Float64:
+		codec.width = int(codec.size)
+	case reflect.String:
+	default:
+		return codec, false
+	}
+	return codec, true
+}
+
+// AppendValue appends the encoded value to the buffer.
+func (c primitiveCodec[T]) AppendValue(buf []byte, value T) ([]byte, error) {
+	return c.append(buf, value), nil
+}
+
+// DecodeValue decodes a value that was encoded by AppendValue.
+func (c primitiveCodec[T]) DecodeValue(data []byte) (T, error) {
+	value, _, err := c.decode(data)
+	return value, err
+}
+
+// append appends the encoded value to the buffer.
+func (c primitiveCodec[T]) append(buf []byte, value T) []byte {
+	ptr := unsafe.Pointer(&value)
+
+	if c.width == 0 { // string
+		s := *(*string)(ptr)
+		buf = binary.AppendUvarint(buf, uint64(len(s)))
+		return append(buf, s...)
+	}
+
+	switch c.size {
+	case 1:
+		return append(buf, *(*uint8)(ptr))
+	case 2:
+		return binary.LittleEndian.AppendUint16(buf, *(*uint16)(ptr))
+	case 4:
+		if c.width == 8 { // int, uint and uintptr on 32 bit platforms
+			v := uint64(*(*uint32)(ptr))
+			if c.kind == reflect.Int {
+				v = uint64(int64(*(*int32)(ptr))) // sign extend
+			}
+			return binary.LittleEndian.AppendUint64(buf, v)
+		}
+		return binary.LittleEndian.AppendUint32(buf, *(*uint32)(ptr))
+	default:
+		return binary.LittleEndian.AppendUint64(buf, *(*uint64)(ptr))
+	}
+}
+
+// decode decodes a value from the start of data and returns the number of bytes read.
+func (c primitiveCodec[T]) decode(data []byte) (T, int, error) {
+	var value T
+	ptr := unsafe.Pointer(&value)
+
+	if c.width == 0 { // string
+		length, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < length {
+			return value, 0, errShortBuffer
+		}
+		*(*string)(ptr) = string(data[n : n+int(length)])
+		return value, n + int(length), nil
+	}
+
+	if len(data) < c.width {
+		return value, 0, errShortBuffer
+	}
+
+	switch c.width {
+	case 1:
+		*(*uint8)(ptr) = data[0]
+	case 2:
+		*(*uint16)(ptr) = binary.LittleEndian.Uint16(data)
+	case 4:
+		*(*uint32)(ptr) = binary.LittleEndian.Uint32(data)
+	default:
+		v := binary.LittleEndian.Uint64(data)
+		if c.size == 4 { // int, uint and uintptr on 32 bit platforms
+			if (c.kind == reflect.Int && (int64(v) < math.MinInt32 || int64(v) > math.MaxInt32)) ||
+				(c.kind != reflect.Int && v > math.MaxUint32) {
+				return value, 0, fmt.Errorf("value %d overflows %s", v, c.kind)
+			}
+			*(*uint32)(ptr) = uint32(v)
+		} else {
+			*(*uint64)(ptr) = v
+		}
+	}
+	return value, c.width, nil
+}
+
+// keyKindWidth returns the width of the encoding of keys of the given kind, 0 for strings.
+// It returns false if the kind is not supported as key.
+func keyKindWidth(kind reflect.Kind) (int, bool) {
+	switch kind {
+	case reflect.Int8, reflect.Uint8:
+		return 1, true
+	case reflect.Int16, reflect.Uint16:
+		return 2, true
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 4, true
+	case reflect.Int, reflect.Uint, reflect.Uintptr, reflect.Int64, reflect.Uint64, reflect.Float64:
+		return 8, true
+	case reflect.String:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// keyEncodedLength returns the length of the encoded key of the given kind at the start of data.
+// It returns false if data is too short or the kind is not supported.
+func keyEncodedLength(kind reflect.Kind, data []byte) (int, bool) {
+	width, ok := keyKindWidth(kind)
+	if !ok {
+		return 0, false
+	}
+	if width > 0 {
+		return width, len(data) >= width
+	}
+
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length {
+		return 0, false
+	}
+	return n + int(length), true
+}
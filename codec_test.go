@@ -0,0 +1,48 @@
+package hashmap
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func testPrimitiveCodec[T any](t *testing.T, values ...T) {
+	t.Helper()
+	codec, ok := newPrimitiveCodec[T]()
+	assert.True(t, ok)
+
+	for _, value := range values {
+		data := codec.append(nil, value)
+		decoded, n, err := codec.decode(data)
+		assert.NoError(t, err)
+		assert.Equal(t, len(data), n)
+		assert.Equal(t, value, decoded)
+	}
+}
+
+func TestPrimitiveCodec(t *testing.T) {
+	t.Parallel()
+	testPrimitiveCodec(t, int(math.MinInt32), 0, int(math.MaxInt32))
+	testPrimitiveCodec(t, int8(math.MinInt8), int8(math.MaxInt8))
+	testPrimitiveCodec(t, uint16(math.MaxUint16))
+	testPrimitiveCodec(t, int32(-1))
+	testPrimitiveCodec(t, uint64(math.MaxUint64))
+	testPrimitiveCodec(t, float32(1.5), float32(-0.1))
+	testPrimitiveCodec(t, 1.5, math.Inf(-1))
+	testPrimitiveCodec(t, "", "value", string(make([]byte, 300)))
+
+	_, ok := newPrimitiveCodec[[]byte]()
+	assert.False(t, ok)
+}
+
+func TestGobCodec(t *testing.T) {
+	t.Parallel()
+	codec := GobCodec[map[string]int]{}
+	data, err := codec.AppendValue([]byte("prefix"), map[string]int{"a": 1})
+	assert.NoError(t, err)
+
+	value, err := codec.DecodeValue(data[len("prefix"):])
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1}, value)
+}
@@ -0,0 +1,350 @@
+package hashmap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tombstoneStripes is the number of stripes that the tombstones and in flight changes are
+// spread over by key hash, it has to be a power of 2.
+const tombstoneStripes = 32
+
+// ErrDeltaUnavailable is returned by WriteDelta if the deletions since the requested
+// version are not retained anymore.
+var ErrDeltaUnavailable = errors.New("delta is not available for the requested version")
+
+// tombstones retains deleted keys for a time window to include them in delta snapshots.
+// Deletions and version assignments are spread over stripes by key hash, to not let
+// concurrent changes of different keys contend on the same lock.
+type tombstones[Key hashable] struct {
+	retention int64 // retention window in nanoseconds
+	stripes   [tombstoneStripes]tombstoneStripe[Key]
+}
+
+// tombstoneStripe holds the retained deletions and the in flight changes of a range of keys.
+type tombstoneStripe[Key hashable] struct {
+	mu sync.Mutex
+	// inflight are the versions of changes that got assigned but are not visible yet
+	// in the elements or tombstones.
+	inflight []uint64
+	entries  tombstoneQueue[Key]
+	pruned   uint64 // highest version of a tombstone of the stripe that is not retained anymore
+	_        [cacheLineSize]byte
+}
+
+// tombstone is a retained deletion of a key.
+type tombstone[Key hashable] struct {
+	hash    uintptr
+	key     Key
+	version uint64
+	deleted int64 // deletion time in nanoseconds
+}
+
+// tombstoneQueue is a ring buffer of tombstones that are ordered by deletion time.
+type tombstoneQueue[Key hashable] struct {
+	entries []tombstone[Key]
+	head    int // index of the oldest tombstone
+	size    int // number of tombstones
+}
+
+// SetTombstoneRetention enables delta snapshots by retaining deleted keys for the given
+// time window. WriteDelta can create deltas for versions that are not older than the window.
+// Expired tombstones are removed by later deletions, WriteDelta and Sweep.
+// It has to be called before the map is used.
+func (m *Map[Key, Value]) SetTombstoneRetention(window time.Duration) {
+	m.enableVersioning()
+	// deletions before enabling the retention are unknown. increasing the version
+	// keeps deltas that start at it apart from full deltas that start at version 0
+	pruned := m.version.Add(1)
+	m.tombstones = &tombstones[Key]{
+		retention: int64(window),
+	}
+	for i := range m.tombstones.stripes {
+		m.tombstones.stripes[i].pruned = pruned
+	}
+	m.enableElementMeta()
+}
+
+// WriteDelta writes a delta snapshot to w that contains all elements that were set and all
+// keys that were deleted after the given map version. A since version of 0 writes all elements.
+// It returns the map version that the delta covers, which is to be passed as since version to
+// the next call. ErrDeltaUnavailable is returned if the deletions after the version are not
+// retained anymore, see SetTombstoneRetention.
+// Elements that are modified while the delta is written may be included in this and the next delta.
+func (m *Map[Key, Value]) WriteDelta(w io.Writer, since uint64) (uint64, error) {
+	if m.tombstones != nil {
+		m.tombstones.prune(nanotime())
+	}
+	if since > 0 && (m.tombstones == nil || since < m.tombstones.prunedVersion()) {
+		return 0, ErrDeltaUnavailable
+	}
+
+	mapVersion := m.coveredVersion()
+	keyCodec, _ := newPrimitiveCodec[Key]()
+	valueCodec := m.getValueCodec()
+
+	sw := &snapshotWriter{w: w}
+	err := sw.writeHeader(snapshotHeader{
+		version:    deltaVersion,
+		keyKind:    keyCodec.kind,
+		hasherID:   m.hasherID(),
+		count:      uint64(m.Len()),
+		since:      since,
+		mapVersion: mapVersion,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var key, value, record []byte
+	for item := m.linkedList.First(); item != nil; item = item.Next() {
+		version := m.elementVersion(item)
+		if (since > 0 && version <= since) || (m.ttl != 0 && m.isExpired(item, nanotime())) {
+			continue
+		}
+
+		key = keyCodec.append(key[:0], item.key)
+		value, err = valueCodec.AppendValue(value[:0], item.Value())
+		if err != nil {
+			return 0, fmt.Errorf("encoding value of key %v: %w", item.key, err)
+		}
+		record = appendRecord(record[:0], deltaVersion, snapshotRecord{
+			hash:    uint64(item.keyHash),
+			version: version,
+			key:     key,
+			value:   value,
+		})
+		if err = sw.writeRecord(record); err != nil {
+			return 0, err
+		}
+	}
+
+	if m.tombstones != nil {
+		for _, deleted := range m.tombstones.since(since) {
+			if element := m.findElement(deleted.hash, deleted.key); element != nil && m.elementVersion(element) > deleted.version {
+				continue // the key was set again after the deletion
+			}
+
+			key = keyCodec.append(key[:0], deleted.key)
+			record = appendRecord(record[:0], deltaVersion, snapshotRecord{
+				hash:    uint64(deleted.hash),
+				version: deleted.version,
+				deleted: true,
+				key:     key,
+			})
+			if err = sw.writeRecord(record); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return mapVersion, sw.close()
+}
+
+// ApplyDelta merges a delta snapshot that was written by WriteDelta into the map.
+// Set elements are overwritten and deleted keys are removed.
+// It returns the number of bytes read.
+func (m *Map[Key, Value]) ApplyDelta(r io.Reader) (int64, error) {
+	return m.ReadFrom(r)
+}
+
+// coveredVersion returns the highest map version up to which all changes are visible.
+func (m *Map[Key, Value]) coveredVersion() uint64 {
+	version := m.version.Load()
+	if m.tombstones == nil {
+		return version
+	}
+	return m.tombstones.covered(version)
+}
+
+// stampVersion assigns the next map version to a changed element. Elements only get
+// versions if tombstones are retained, otherwise just the map version is increased.
+func (m *Map[Key, Value]) stampVersion(element *ListElement[Key, Value]) {
+	if m.tombstones == nil {
+		m.increaseVersion()
+		return
+	}
+
+	stripe := m.tombstones.stripe(element.keyHash)
+	version := stripe.begin(&m.version)
+	element.meta().version.Store(version)
+	stripe.end(version)
+}
+
+// elementVersion returns the map version of the last change of the element, it is 0 if
+// tombstones are not retained or the element was not changed since enabling the retention.
+func (m *Map[Key, Value]) elementVersion(element *ListElement[Key, Value]) uint64 {
+	if m.tombstones == nil {
+		return 0
+	}
+	return element.meta().version.Load()
+}
+
+// findElement returns the element for the key or nil if it does not exist.
+func (m *Map[Key, Value]) findElement(hash uintptr, key Key) *ListElement[Key, Value] {
+	for element := m.store.Load().item(hash); element != nil; element = element.Next() {
+		if element.keyHash == hash && element.key == key {
+			return element
+		}
+		if element.keyHash > hash {
+			return nil
+		}
+	}
+	return nil
+}
+
+// stripe returns the stripe for the hashed key.
+func (t *tombstones[Key]) stripe(hash uintptr) *tombstoneStripe[Key] {
+	return &t.stripes[hash&(tombstoneStripes-1)]
+}
+
+// add assigns the next map version to the deletion of a key, retains it and removes
+// tombstones of the stripe that passed the retention window. The tombstone is visible to
+// covered as soon as its version is assigned, it does not need to be registered as in flight.
+func (t *tombstones[Key]) add(hash uintptr, key Key, version *atomic.Uint64) {
+	now := nanotime()
+	stripe := t.stripe(hash)
+
+	stripe.mu.Lock()
+	stripe.entries.push(tombstone[Key]{
+		hash:    hash,
+		key:     key,
+		version: version.Add(1),
+		deleted: now,
+	})
+	stripe.prune(now, t.retention)
+	stripe.mu.Unlock()
+}
+
+// prune removes the tombstones of all stripes that passed the retention window.
+func (t *tombstones[Key]) prune(now int64) {
+	for i := range t.stripes {
+		stripe := &t.stripes[i]
+		stripe.mu.Lock()
+		stripe.prune(now, t.retention)
+		stripe.mu.Unlock()
+	}
+}
+
+// covered returns the highest version up to the given current map version that no change
+// in flight has a version of or below.
+func (t *tombstones[Key]) covered(version uint64) uint64 {
+	for i := range t.stripes {
+		stripe := &t.stripes[i]
+		stripe.mu.Lock()
+		for _, inflight := range stripe.inflight {
+			version = min(version, inflight-1)
+		}
+		stripe.mu.Unlock()
+	}
+	return version
+}
+
+// since returns all retained tombstones with a version after the given one, sorted by hash.
+func (t *tombstones[Key]) since(version uint64) []tombstone[Key] {
+	var result []tombstone[Key]
+	for i := range t.stripes {
+		stripe := &t.stripes[i]
+		stripe.mu.Lock()
+		for j := range stripe.entries.size {
+			if entry := stripe.entries.at(j); entry.version > version {
+				result = append(result, entry)
+			}
+		}
+		stripe.mu.Unlock()
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].hash < result[j].hash
+	})
+	return result
+}
+
+// prunedVersion returns the highest version of a deletion that is not retained anymore.
+func (t *tombstones[Key]) prunedVersion() uint64 {
+	var pruned uint64
+	for i := range t.stripes {
+		stripe := &t.stripes[i]
+		stripe.mu.Lock()
+		pruned = max(pruned, stripe.pruned)
+		stripe.mu.Unlock()
+	}
+	return pruned
+}
+
+// begin assigns the next map version to a change and registers it as in flight until
+// end is called. Assigning and registering the version under the lock of the stripe
+// makes the version visible to covered before a later version can be loaded.
+func (s *tombstoneStripe[Key]) begin(version *atomic.Uint64) uint64 {
+	s.mu.Lock()
+	next := version.Add(1)
+	s.inflight = append(s.inflight, next)
+	s.mu.Unlock()
+	return next
+}
+
+// end marks the change with the version as visible.
+func (s *tombstoneStripe[Key]) end(version uint64) {
+	s.mu.Lock()
+	for i, inflight := range s.inflight {
+		if inflight == version {
+			last := len(s.inflight) - 1
+			s.inflight[i] = s.inflight[last]
+			s.inflight = s.inflight[:last]
+			break
+		}
+	}
+	s.mu.Unlock()
+}
+
+// prune removes the tombstones that passed the retention window, the lock has to be held.
+func (s *tombstoneStripe[Key]) prune(now, retention int64) {
+	for s.entries.size > 0 {
+		oldest := s.entries.at(0)
+		if now-oldest.deleted <= retention {
+			return
+		}
+		s.pruned = max(s.pruned, oldest.version)
+		s.entries.pop()
+	}
+}
+
+// push appends a tombstone, the buffer grows if it is full.
+func (q *tombstoneQueue[Key]) push(entry tombstone[Key]) {
+	if q.size == len(q.entries) {
+		entries := make([]tombstone[Key], max(2*len(q.entries), 8))
+		for i := range q.size {
+			entries[i] = q.at(i)
+		}
+		q.entries = entries
+		q.head = 0
+	}
+	q.entries[(q.head+q.size)%len(q.entries)] = entry
+	q.size++
+}
+
+// pop removes the oldest tombstone, the buffer shrinks if it is mostly empty.
+func (q *tombstoneQueue[Key]) pop() {
+	q.entries[q.head] = tombstone[Key]{} // release the key
+	q.head = (q.head + 1) % len(q.entries)
+	q.size--
+
+	if len(q.entries) > 8 && q.size <= len(q.entries)/4 {
+		entries := make([]tombstone[Key], len(q.entries)/2)
+		for i := range q.size {
+			entries[i] = q.at(i)
+		}
+		q.entries = entries
+		q.head = 0
+	}
+}
+
+// at returns the tombstone at the position, 0 is the oldest one.
+func (q *tombstoneQueue[Key]) at(i int) tombstone[Key] {
+	return q.entries[(q.head+i)%len(q.entries)]
+}
@@ -0,0 +1,255 @@
+package hashmap
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestWriteDelta(t *testing.T) {
+	t.Parallel()
+	m := New[int, string]()
+	m.SetTombstoneRetention(time.Hour)
+	for i := range 100 {
+		m.Set(i, "initial")
+	}
+
+	var full bytes.Buffer
+	version, err := m.WriteDelta(&full, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, m.Version(), version)
+	fullSize := full.Len()
+
+	replica := New[int, string]()
+	_, err = replica.ApplyDelta(&full)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, replica.Len())
+
+	m.Set(1, "updated")
+	m.Del(2)
+	m.Del(3)
+	m.Set(3, "recreated")
+	m.Set(200, "added")
+
+	var delta bytes.Buffer
+	version, err = m.WriteDelta(&delta, version)
+	assert.NoError(t, err)
+	assert.Equal(t, m.Version(), version)
+	assert.True(t, delta.Len() < fullSize)
+
+	_, err = replica.ApplyDelta(&delta)
+	assert.NoError(t, err)
+	assert.Equal(t, m.Len(), replica.Len())
+	m.Range(func(key int, value string) bool {
+		replicaValue, ok := replica.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, value, replicaValue)
+		return true
+	})
+	_, ok := replica.Get(2)
+	assert.False(t, ok)
+
+	var empty bytes.Buffer
+	next, err := m.WriteDelta(&empty, version)
+	assert.NoError(t, err)
+	assert.Equal(t, version, next)
+	_, err = replica.ApplyDelta(&empty)
+	assert.NoError(t, err)
+	assert.Equal(t, m.Len(), replica.Len())
+}
+
+func TestWriteDeltaUnavailable(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.Set(1, 1)
+
+	var buf bytes.Buffer
+	_, err := m.WriteDelta(&buf, 1)
+	assert.ErrorIs(t, err, ErrDeltaUnavailable)
+
+	m.SetTombstoneRetention(time.Nanosecond)
+	_, err = m.WriteDelta(&buf, 0)
+	assert.NoError(t, err)
+
+	m.Set(2, 2)
+	version := m.Version()
+	m.Del(1)
+	time.Sleep(time.Millisecond)
+	m.Del(2) // prunes the tombstone of key 1
+
+	_, err = m.WriteDelta(&buf, version)
+	assert.ErrorIs(t, err, ErrDeltaUnavailable)
+}
+
+func TestWriteDeltaExistingElements(t *testing.T) {
+	t.Parallel()
+	b := NewBuilder[int, int](10)
+	for i := range 10 {
+		b.Add(i, i)
+	}
+	m := b.Build()
+	m.SetTombstoneRetention(time.Hour)
+
+	var full bytes.Buffer
+	version, err := m.WriteDelta(&full, 0)
+	assert.NoError(t, err)
+	replica := New[int, int]()
+	_, err = replica.ApplyDelta(&full)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, replica.Len())
+
+	m.Set(1, 100)
+	var delta bytes.Buffer
+	_, err = m.WriteDelta(&delta, version)
+	assert.NoError(t, err)
+	reader, err := NewSnapshotReader(&delta)
+	assert.NoError(t, err)
+	entry, err := reader.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), entry.Key)
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestWriteDeltaConcurrentWriters(t *testing.T) {
+	t.Parallel()
+	// every writer changes its own range of list elements that starts with a key that is
+	// never deleted, writers do not insert next to elements that others delete concurrently
+	m := NewSized[int, int](4096)
+	m.SetHasher(func(key int) uintptr {
+		return uintptr(key) << (strconv.IntSize - 10)
+	})
+	m.SetTombstoneRetention(time.Hour)
+	replica := New[int, int]()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := range 8 {
+		m.Set(g*100, g)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				key := g*100 + 1 + i%99
+				if i%3 == 0 {
+					m.Del(key)
+				} else {
+					m.Set(key, i)
+				}
+			}
+		}()
+	}
+
+	var version uint64
+	first := uint64(0)
+	for i := range 20 {
+		var buf bytes.Buffer
+		next, err := m.WriteDelta(&buf, version)
+		assert.NoError(t, err)
+		assert.True(t, next >= version)
+		if i == 0 {
+			first = next
+		}
+		version = next
+		_, err = replica.ApplyDelta(&buf)
+		assert.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, version > first) // deltas make progress while writes are in flight
+
+	close(done)
+	wg.Wait()
+	var buf bytes.Buffer
+	_, err := m.WriteDelta(&buf, version)
+	assert.NoError(t, err)
+	_, err = replica.ApplyDelta(&buf)
+	assert.NoError(t, err)
+
+	assert.Equal(t, m.Len(), replica.Len())
+	m.Range(func(key, value int) bool {
+		replicaValue, ok := replica.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, value, replicaValue)
+		return true
+	})
+}
+
+func TestTombstonePruning(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.SetTombstoneRetention(time.Millisecond)
+	for i := range 100 {
+		m.Set(i, i)
+	}
+	for i := range 100 {
+		m.Del(i)
+	}
+	assert.Equal(t, 100, len(m.tombstones.since(0)))
+
+	time.Sleep(5 * time.Millisecond)
+	m.Sweep() // prunes without further deletions
+	assert.Equal(t, 0, len(m.tombstones.since(0)))
+	for i := range m.tombstones.stripes {
+		assert.True(t, len(m.tombstones.stripes[i].entries.entries) <= 8)
+	}
+}
+
+func TestTombstoneQueue(t *testing.T) {
+	t.Parallel()
+	var q tombstoneQueue[int]
+	next, oldest := 0, 0
+	for round := range 10 {
+		for range 5 + round {
+			q.push(tombstone[int]{key: next})
+			next++
+		}
+		for range 3 {
+			assert.Equal(t, oldest, q.at(0).key)
+			q.pop()
+			oldest++
+		}
+		for i := range q.size {
+			assert.Equal(t, oldest+i, q.at(i).key)
+		}
+	}
+	assert.Equal(t, next-oldest, q.size)
+}
+
+func TestConcurrentRemoveElement(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.SetTombstoneRetention(time.Hour)
+	m.SetMemoryBudget(func(int, int) int64 { return 1 }, 100)
+	m.Set(1, 1)
+	element := m.findElement(m.hasher(1), 1)
+	version := m.Version()
+
+	var wg sync.WaitGroup
+	var removed atomic.Int64
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if m.removeElement(element) {
+				removed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, removed.Load())
+	assert.Equal(t, version+1, m.Version())
+	assert.Equal(t, 1, len(m.tombstones.since(0)))
+	assert.Equal(t, 0, m.Weight())
+}
@@ -0,0 +1,141 @@
+package hashmap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// hashDigits is the number of hex digits of a hash.
+const hashDigits = strconv.IntSize / 4
+
+// dotLabelEscaper escapes the characters that have a special meaning in DOT record labels.
+var dotLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `{`, `\{`, `}`, `\}`,
+	`|`, `\|`, `<`, `\<`, `>`, `\>`, "\n", `\n`)
+
+// dumpElement is a list element with its position in the raw list.
+type dumpElement[Key comparable, Value any] struct {
+	element *ListElement[Key, Value]
+	id      int
+}
+
+// dumpStructure walks the raw list including deleted elements and the index of the map.
+// It returns the elements in list order and the ids of all elements by pointer.
+func (m *Map[Key, Value]) dumpStructure() ([]dumpElement[Key, Value], map[*ListElement[Key, Value]]int) {
+	var elements []dumpElement[Key, Value]
+	ids := map[*ListElement[Key, Value]]int{}
+	for element := m.linkedList.head.next.Load(); element != nil; element = element.next.Load() {
+		ids[element] = len(elements)
+		elements = append(elements, dumpElement[Key, Value]{element: element, id: len(elements)})
+	}
+	return elements, ids
+}
+
+// Dump writes a text representation of the list and index structure of the map to w,
+// including deleted elements that are still linked. It is intended for debugging small maps.
+func (m *Map[Key, Value]) Dump(w io.Writer) error {
+	store := m.store.Load()
+	elements, ids := m.dumpStructure()
+
+	// slots that point at each element
+	slots := map[*ListElement[Key, Value]][]uintptr{}
+	var dangling []uintptr
+	occupied := 0
+	for slot := range uintptr(len(store.index)) {
+		element := store.item(slot << store.keyShifts)
+		if element == nil {
+			continue
+		}
+		occupied++
+		if _, ok := ids[element]; !ok {
+			dangling = append(dangling, slot)
+			continue
+		}
+		slots[element] = append(slots[element], slot)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "index: %d slots, %d occupied, count %d\n", len(store.index), occupied, store.count.Load())
+	fmt.Fprintf(bw, "list: %d elements, count %d\n", len(elements), m.linkedList.Len())
+	for _, item := range elements {
+		element := item.element
+		fmt.Fprintf(bw, "%4d %0*x slot %d key %v", item.id, hashDigits, element.keyHash,
+			element.keyHash>>store.keyShifts, element.key)
+		if element.deleted.Load() != 0 {
+			bw.WriteString(" deleted")
+		}
+		for _, slot := range slots[element] {
+			fmt.Fprintf(bw, " <- slot %d", slot)
+		}
+		bw.WriteByte('\n')
+	}
+	for _, slot := range dangling {
+		element := store.item(slot << store.keyShifts)
+		fmt.Fprintf(bw, "slot %d -> unlinked element %0*x key %v\n", slot, hashDigits, element.keyHash, element.key)
+	}
+	return bw.Flush()
+}
+
+// DumpDOT writes the list and index structure of the map to w as Graphviz DOT graph.
+// It renders the list head, all linked elements including deleted ones and the occupied
+// index slots with their pointers into the list.
+func (m *Map[Key, Value]) DumpDOT(w io.Writer) error {
+	store := m.store.Load()
+	elements, ids := m.dumpStructure()
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph hashmap {\n")
+	bw.WriteString("\trankdir=LR;\n")
+	bw.WriteString("\tnode [shape=record, fontname=monospace];\n")
+	bw.WriteString("\thead [label=\"head\", shape=box];\n")
+
+	previous := "head"
+	for _, item := range elements {
+		writeDOTElement(bw, fmt.Sprintf("e%d", item.id), item.element, "")
+		fmt.Fprintf(bw, "\t%s -> e%d;\n", previous, item.id)
+		previous = fmt.Sprintf("e%d", item.id)
+	}
+
+	bw.WriteString("\tsubgraph cluster_index {\n")
+	fmt.Fprintf(bw, "\t\tlabel=\"index (%d slots)\";\n", len(store.index))
+	var edges []string
+	for slot := range uintptr(len(store.index)) {
+		element := store.item(slot << store.keyShifts)
+		if element == nil {
+			continue
+		}
+		fmt.Fprintf(bw, "\t\ts%d [label=\"slot %d\", shape=box];\n", slot, slot)
+
+		id, ok := ids[element]
+		target := fmt.Sprintf("e%d", id)
+		if !ok {
+			target = fmt.Sprintf("u%d", slot)
+			writeDOTElement(bw, target, element, "unlinked")
+		}
+		edges = append(edges, fmt.Sprintf("\ts%d -> %s [style=dashed];\n", slot, target))
+	}
+	bw.WriteString("\t}\n")
+	for _, edge := range edges {
+		bw.WriteString(edge)
+	}
+
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// writeDOTElement writes the node of a list element.
+func writeDOTElement[Key comparable, Value any](w io.Writer, name string, element *ListElement[Key, Value], note string) {
+	label := fmt.Sprintf("{%0*x|%s", hashDigits, element.keyHash, dotLabelEscaper.Replace(fmt.Sprint(element.key)))
+	style := ""
+	if element.deleted.Load() != 0 {
+		label += "|deleted"
+		style = ", style=dashed, color=gray"
+	}
+	if note != "" {
+		label += "|" + note
+		style = ", color=red"
+	}
+	fmt.Fprintf(w, "\t%s [label=\"%s}\"%s];\n", name, label, style)
+}
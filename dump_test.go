@@ -0,0 +1,77 @@
+package hashmap
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+// newDumpTestMap returns a map whose keys are stored in the index slot of the same number.
+func newDumpTestMap() *Map[uintptr, string] {
+	m := NewSized[uintptr, string](4)
+	m.SetHasher(func(key uintptr) uintptr {
+		return key << (strconv.IntSize - 2)
+	})
+	m.Set(0, "a")
+	m.Set(2, "b")
+	m.Set(3, "c")
+	return m
+}
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+	m := newDumpTestMap()
+	m.linkedList.First().Next().deleted.Store(1) // deleted element that is still linked
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.Dump(&buf))
+
+	hash := func(key uintptr) string {
+		return fmt.Sprintf("%0*x", hashDigits, key<<(strconv.IntSize-2))
+	}
+	expected := "index: 4 slots, 3 occupied, count 3\n" +
+		"list: 3 elements, count 3\n" +
+		"   0 " + hash(0) + " slot 0 key 0 <- slot 0\n" +
+		"   1 " + hash(2) + " slot 2 key 2 deleted <- slot 2\n" +
+		"   2 " + hash(3) + " slot 3 key 3 <- slot 3\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestDumpDOT(t *testing.T) {
+	t.Parallel()
+	m := newDumpTestMap()
+	m.Del(2)
+	m.Range(func(uintptr, string) bool { return true }) // unlinks the deleted element
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.DumpDOT(&buf))
+	output := buf.String()
+
+	assert.True(t, strings.HasPrefix(output, "digraph hashmap {\n"))
+	assert.True(t, strings.HasSuffix(output, "}\n"))
+	for _, line := range []string{
+		"\thead -> e0;\n",
+		"\te0 -> e1;\n",
+		"\ts0 -> e0 [style=dashed];\n",
+		"\ts3 -> e1 [style=dashed];\n",
+		`label="index (4 slots)"`,
+	} {
+		assert.True(t, strings.Contains(output, line), line)
+	}
+	assert.False(t, strings.Contains(output, "s2 "))
+	assert.False(t, strings.Contains(output, "e2"))
+}
+
+func TestDumpDOTEscaping(t *testing.T) {
+	t.Parallel()
+	m := New[string, int]()
+	m.Set(`{a|"b"}`, 1)
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.DumpDOT(&buf))
+	assert.True(t, strings.Contains(buf.String(), `\{a\|\"b\"\}`))
+}
@@ -0,0 +1,104 @@
+package hashmap
+
+import (
+	"time"
+)
+
+// processStart is the reference point of nanotime.
+var processStart = time.Now()
+
+// nanotime returns the time in nanoseconds since the process started. It is based on the
+// monotonic clock, deadlines are therefore not affected by changes of the wall clock.
+func nanotime() int64 {
+	return int64(time.Since(processStart))
+}
+
+// SetSlidingExpiration enables idle timeout semantics for the map: every write and every
+// Get or Touch of a key extends its expiry to ttl from now. Expired keys are not returned
+// anymore and get removed from the map by Sweep. Until then they are still counted by Len.
+// A ttl of 0 disables the expiration. It has to be called before the map is used.
+func (m *Map[Key, Value]) SetSlidingExpiration(ttl time.Duration) {
+	m.ttl = int64(ttl)
+	m.enableElementMeta()
+}
+
+// Touch extends the expiry of the key without reading its value.
+// Returns false if the key does not exist or is expired.
+func (m *Map[Key, Value]) Touch(key Key) bool {
+	hash := m.hasher(key)
+
+	for element := m.store.Load().item(hash); element != nil; element = element.Next() {
+		if element.keyHash == hash && element.key == key {
+			return m.ttl == 0 || m.touch(element, nanotime())
+		}
+
+		if element.keyHash > hash {
+			return false
+		}
+	}
+	return false
+}
+
+// Sweep removes all expired elements from the map and returns the number of removed elements.
+// It also removes the tombstones that passed their retention window, see SetTombstoneRetention.
+func (m *Map[Key, Value]) Sweep() int {
+	if m.tombstones != nil {
+		m.tombstones.prune(nanotime())
+	}
+	if m.ttl == 0 {
+		return 0
+	}
+
+	now := nanotime()
+	removed := 0
+	for item := m.linkedList.First(); item != nil; item = item.Next() {
+		if m.isExpired(item, now) && m.removeElement(item) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// StartSweeper starts a goroutine that calls Sweep in the given interval.
+// The returned function stops the sweeper.
+func (m *Map[Key, Value]) StartSweeper(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.Sweep()
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// touch extends the deadline of the element unless it is already expired.
+// It returns whether the element is still alive.
+func (m *Map[Key, Value]) touch(element *ListElement[Key, Value], now int64) bool {
+	meta := element.meta()
+	for {
+		deadline := meta.deadline.Load()
+		if deadline != 0 && deadline <= now {
+			return false
+		}
+		if meta.deadline.CompareAndSwap(deadline, now+m.ttl) {
+			return true
+		}
+	}
+}
+
+// isExpired returns whether the deadline of the element has passed.
+func (m *Map[Key, Value]) isExpired(element *ListElement[Key, Value], now int64) bool {
+	deadline := element.meta().deadline.Load()
+	return deadline != 0 && deadline <= now
+}
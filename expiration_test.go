@@ -0,0 +1,102 @@
+package hashmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestSlidingExpiration(t *testing.T) {
+	t.Parallel()
+	m := New[int, string]()
+	m.SetSlidingExpiration(200 * time.Millisecond)
+
+	m.Set(1, "a")
+	assert.True(t, m.Insert(2, "b"))
+
+	for range 5 { // keep the first key alive by accessing it
+		time.Sleep(50 * time.Millisecond)
+		_, ok := m.Get(1)
+		assert.True(t, ok)
+	}
+
+	_, ok := m.Get(2)
+	assert.False(t, ok)
+	assert.Equal(t, 2, m.Len())
+
+	assert.Equal(t, 1, m.Sweep())
+	assert.Equal(t, 1, m.Len())
+
+	assert.True(t, m.Insert(2, "c")) // expired keys can be inserted again
+	value, ok := m.Get(2)
+	assert.True(t, ok)
+	assert.Equal(t, "c", value)
+}
+
+func TestTouch(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.SetSlidingExpiration(200 * time.Millisecond)
+	assert.False(t, m.Touch(1))
+
+	m.Set(1, 1)
+	for range 5 {
+		time.Sleep(50 * time.Millisecond)
+		assert.True(t, m.Touch(1))
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	assert.False(t, m.Touch(1))
+
+	m.Set(1, 2) // an expired key gets replaced
+	value, ok := m.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, 1, m.Len())
+}
+
+func TestSweeper(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.SetSlidingExpiration(time.Millisecond)
+	stop := m.StartSweeper(time.Millisecond)
+	defer stop()
+
+	for i := range 10 {
+		m.Set(i, i)
+	}
+	waitFor(t, func() bool {
+		return m.Len() == 0
+	})
+}
+
+func TestSlidingExpirationExistingElements(t *testing.T) {
+	t.Parallel()
+	b := NewBuilder[int, int](10)
+	for i := range 10 {
+		b.Add(i, i)
+	}
+	m := b.Build()
+	m.SetSlidingExpiration(time.Hour) // boxes the values of the existing elements
+
+	for i := range 10 {
+		assert.True(t, m.Touch(i))
+		value, ok := m.Get(i)
+		assert.True(t, ok)
+		assert.Equal(t, i, value)
+	}
+	m.Set(1, 100)
+	value, ok := m.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, 100, value)
+	assert.Equal(t, 0, m.Sweep())
+}
+
+func TestNanotime(t *testing.T) {
+	t.Parallel()
+	first := nanotime()
+	assert.True(t, first >= 0)
+	time.Sleep(time.Millisecond)
+	assert.True(t, nanotime()-first >= int64(time.Millisecond))
+}
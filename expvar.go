@@ -0,0 +1,54 @@
+package hashmap
+
+import (
+	"expvar"
+)
+
+// expvarStats is the JSON representation of the statistics of a published map.
+type expvarStats struct {
+	Len        int               `json:"len"`
+	FillRate   int               `json:"fill_rate"`
+	IndexSize  int               `json:"index_size"`
+	Resizes    uint64            `json:"resizes"`
+	Operations *expvarOperations `json:"operations,omitempty"` // only set if stats are enabled
+}
+
+// expvarOperations contains the operation counters of a published map.
+type expvarOperations struct {
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Inserts    uint64 `json:"inserts"`
+	Updates    uint64 `json:"updates"`
+	Deletes    uint64 `json:"deletes"`
+	CASRetries uint64 `json:"cas_retries"`
+}
+
+// Publish registers an expvar variable with the given name that exposes the statistics of the
+// map as JSON under /debug/vars. The operation counters are included if EnableStats was called.
+// Like expvar.Publish, it panics if the name is already registered.
+func Publish[Key hashable, Value any](name string, m *Map[Key, Value]) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return m.expvarStats()
+	}))
+}
+
+func (m *Map[Key, Value]) expvarStats() expvarStats {
+	stats := m.Stats()
+	result := expvarStats{
+		Len:       stats.Len,
+		FillRate:  stats.FillRate,
+		IndexSize: stats.IndexSize,
+		Resizes:   stats.Resizes,
+	}
+	if stats.Counting {
+		result.Operations = &expvarOperations{
+			Hits:       stats.Hits,
+			Misses:     stats.Misses,
+			Inserts:    stats.Inserts,
+			Updates:    stats.Updates,
+			Deletes:    stats.Deletes,
+			CASRetries: stats.CASRetries,
+		}
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package hashmap
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestPublish(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.EnableStats()
+	Publish("hashmap_test_publish", m)
+
+	m.Set(1, 1)
+	m.Get(1)
+	m.Get(2)
+
+	var stats struct {
+		Len        int            `json:"len"`
+		IndexSize  int            `json:"index_size"`
+		Operations map[string]int `json:"operations"`
+	}
+	err := json.Unmarshal([]byte(expvar.Get("hashmap_test_publish").String()), &stats)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Len)
+	assert.Equal(t, defaultSize, stats.IndexSize)
+	assert.Equal(t, 1, stats.Operations["hits"])
+	assert.Equal(t, 1, stats.Operations["misses"])
+	assert.Equal(t, 1, stats.Operations["inserts"])
+}
+
+func TestPublishWithoutStats(t *testing.T) {
+	t.Parallel()
+	m := New[string, int]()
+	Publish("hashmap_test_publish_without_stats", m)
+
+	var stats map[string]any
+	err := json.Unmarshal([]byte(expvar.Get("hashmap_test_publish_without_stats").String()), &stats)
+	assert.NoError(t, err)
+	_, ok := stats["operations"]
+	assert.False(t, ok)
+	assert.Equal(t, 0.0, stats["len"])
+}
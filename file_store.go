@@ -0,0 +1,101 @@
+package hashmap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// fileStoreMaxName is the maximum length of a hex encoded key that is used as file name,
+// it stays below the limit of 255 bytes of most file systems.
+const fileStoreMaxName = 200
+
+// FileStore is a Store that persists every key as a gob encoded file in a directory.
+// It is intended as a reference implementation and for testing.
+type FileStore[Key hashable, Value any] struct {
+	dir string
+}
+
+// NewFileStore returns a new file store that uses the given directory, the directory gets created if needed.
+func NewFileStore[Key hashable, Value any](dir string) (*FileStore[Key, Value], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating store directory: %w", err)
+	}
+	return &FileStore[Key, Value]{dir: dir}, nil
+}
+
+// Load returns the value for the key, the bool reports whether the key exists in the store.
+func (s *FileStore[Key, Value]) Load(key Key) (Value, bool, error) {
+	var value Value
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return value, false, nil
+		}
+		return value, false, fmt.Errorf("reading value file: %w", err)
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return value, false, fmt.Errorf("decoding value: %w", err)
+	}
+	return value, true, nil
+}
+
+// Save persists the value under the key. The file is replaced atomically.
+func (s *FileStore[Key, Value]) Save(key Key, value Value) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return fmt.Errorf("encoding value: %w", err)
+	}
+
+	// every save uses its own temporary file, concurrent saves of the same key
+	// can then not mix up their contents
+	file, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating value file: %w", err)
+	}
+	_, err = file.Write(buf.Bytes())
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0o644)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("writing value file: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), s.path(key)); err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("renaming value file: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the key from the store.
+func (s *FileStore[Key, Value]) Delete(key Key) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing value file: %w", err)
+	}
+	return nil
+}
+
+// path returns the file name for the key, the key is hex encoded to be usable as a file name.
+// Keys that would exceed the file name length limit of common file systems are hashed instead,
+// the "h" prefix keeps these names apart from hex encoded keys.
+func (s *FileStore[Key, Value]) path(key Key) string {
+	formatted := []byte(fmt.Sprint(key))
+	name := hex.EncodeToString(formatted)
+	if len(name) > fileStoreMaxName {
+		sum := sha256.Sum256(formatted)
+		name = "h" + hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(s.dir, name)
+}
@@ -0,0 +1,103 @@
+package hashmap
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+	store, err := NewFileStore[string, []string](t.TempDir())
+	assert.NoError(t, err)
+
+	_, ok, err := store.Load("key")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, store.Save("key", []string{"a", "b"}))
+	value, ok, err := store.Load("key")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b"}, value)
+
+	assert.NoError(t, store.Delete("key"))
+	assert.NoError(t, store.Delete("key"))
+	_, ok, err = store.Load("key")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestFileStoreWriteBehind(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := NewFileStore[int, string](dir)
+	assert.NoError(t, err)
+
+	s := NewWriteBehind(New[int, string](), store, WriteBehindOptions{})
+	assert.NoError(t, s.Set(1, "one"))
+	assert.NoError(t, s.Set(2, "two"))
+	assert.NoError(t, s.Del(2))
+	assert.NoError(t, s.Close(context.Background()))
+
+	s = NewWriteThrough(New[int, string](), store) // reopen with an empty map
+	value, ok, err := s.Get(1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "one", value)
+	_, ok, err = s.Get(2)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestFileStoreLongKey(t *testing.T) {
+	t.Parallel()
+	store, err := NewFileStore[string, int](t.TempDir())
+	assert.NoError(t, err)
+
+	long := strings.Repeat("k", 200)
+	assert.NoError(t, store.Save(long, 1))
+	assert.NoError(t, store.Save(long+"x", 2))
+
+	value, ok, err := store.Load(long)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	value, ok, err = store.Load(long + "x")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+}
+
+func TestFileStoreConcurrentSave(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := NewFileStore[int, string](dir)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				assert.NoError(t, store.Save(1, strings.Repeat(strconv.Itoa(i), 1000)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, ok, err := store.Load(1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, strings.Repeat(value[:1], 1000), value)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries)) // no temporary files are left behind
+}
@@ -0,0 +1,102 @@
+package hashmap
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// defaultFormatLimit is the default number of entries that Format prints.
+const defaultFormatLimit = 32
+
+// SetFormatLimit sets the maximum number of entries that are printed when formatting the map
+// using the %v verb, the remaining entries are summarized. A precision like %.5v overrides it.
+// A limit of 0 resets it to the default, a negative limit prints all entries.
+func (m *Map[Key, Value]) SetFormatLimit(limit int) {
+	m.formatLimit = limit
+}
+
+// Format implements fmt.Formatter. The verb %v prints keys and values, %+v adds the statistics
+// of the map and the hashes of the keys and %#v prints a Go syntax representation.
+// All other verbs format the hashes returned by String, like %s, %q or %x.
+func (m *Map[Key, Value]) Format(f fmt.State, verb rune) {
+	if verb != 'v' {
+		_, _ = fmt.Fprintf(f, fmt.FormatString(f, verb), m.String())
+		return
+	}
+
+	limit := m.formatLimit
+	if limit == 0 {
+		limit = defaultFormatLimit
+	}
+	if precision, ok := f.Precision(); ok {
+		limit = precision
+	}
+
+	var b strings.Builder
+	switch {
+	case f.Flag('#'):
+		b.WriteString("&" + strings.TrimPrefix(fmt.Sprintf("%T", m), "*") + "{")
+	case f.Flag('+'):
+		stats := m.Stats()
+		fmt.Fprintf(&b, "map[len:%d index:%d fill:%d%% resizes:%d version:%d;",
+			stats.Len, stats.IndexSize, stats.FillRate, stats.Resizes, m.Version())
+	default:
+		b.WriteString("map[")
+	}
+
+	printed := 0
+	m.Range(func(key Key, value Value) bool {
+		if limit >= 0 && printed == limit {
+			return false
+		}
+		switch {
+		case f.Flag('#'):
+			if printed > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%#v:%#v", key, value)
+		case f.Flag('+'):
+			fmt.Fprintf(&b, " %v:%v@%0*x", key, value, hashDigits, m.hasher(key))
+		default:
+			if printed > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%v:%v", key, value)
+		}
+		printed++
+		return true
+	})
+
+	if remaining := m.Len() - printed; remaining > 0 && limit >= 0 && printed == limit {
+		if f.Flag('#') {
+			fmt.Fprintf(&b, " /* %d more */", remaining)
+		} else {
+			fmt.Fprintf(&b, " ...+%d", remaining)
+		}
+	}
+	if f.Flag('#') {
+		b.WriteByte('}')
+	} else {
+		b.WriteByte(']')
+	}
+	_, _ = f.Write([]byte(b.String()))
+}
+
+// LogValue implements slog.LogValuer and returns a summary of the map instead of its entries.
+func (m *Map[Key, Value]) LogValue() slog.Value {
+	stats := m.Stats()
+	attrs := []slog.Attr{
+		slog.Int("len", stats.Len),
+		slog.Int("index_size", stats.IndexSize),
+		slog.Int("fill_rate", stats.FillRate),
+		slog.Uint64("resizes", stats.Resizes),
+	}
+	if stats.Counting {
+		attrs = append(attrs,
+			slog.Uint64("hits", stats.Hits),
+			slog.Uint64("misses", stats.Misses),
+		)
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,75 @@
+package hashmap
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+// newFormatTestMap returns a map that iterates its keys in ascending order.
+func newFormatTestMap(count int) *Map[int, string] {
+	m := New[int, string]()
+	m.SetHasher(func(key int) uintptr {
+		return uintptr(key)
+	})
+	for i := range count {
+		m.Set(i, fmt.Sprintf("v%d", i))
+	}
+	return m
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	m := newFormatTestMap(3)
+
+	assert.Equal(t, "map[0:v0 1:v1 2:v2]", fmt.Sprintf("%v", m))
+	assert.Equal(t, "map[0:v0 1:v1 ...+1]", fmt.Sprintf("%.2v", m))
+	assert.Equal(t, `&hashmap.Map[int,string]{0:"v0", 1:"v1", 2:"v2"}`, fmt.Sprintf("%#v", m))
+	assert.Equal(t, `&hashmap.Map[int,string]{0:"v0" /* 2 more */}`, fmt.Sprintf("%#.1v", m))
+	assert.Equal(t, m.String(), fmt.Sprintf("%s", m))
+	assert.Equal(t, fmt.Sprintf("%q", m.String()), fmt.Sprintf("%q", m))
+	assert.Equal(t, fmt.Sprintf("%x", m.String()), fmt.Sprintf("%x", m))
+	assert.Equal(t, fmt.Sprintf("%-40s|", m.String()), fmt.Sprintf("%-40s|", m))
+
+	extended := fmt.Sprintf("%+v", m)
+	assert.True(t, strings.HasPrefix(extended, "map[len:3 index:8 fill:"), extended)
+	assert.True(t, strings.HasSuffix(extended, fmt.Sprintf(" 2:v2@%0*x]", hashDigits, 2)), extended)
+}
+
+func TestFormatLimit(t *testing.T) {
+	t.Parallel()
+	m := newFormatTestMap(100)
+	output := fmt.Sprintf("%v", m)
+	assert.Equal(t, defaultFormatLimit, strings.Count(output, ":"))
+	assert.True(t, strings.HasSuffix(output, fmt.Sprintf(" ...+%d]", 100-defaultFormatLimit)), output)
+
+	m.SetFormatLimit(1)
+	assert.Equal(t, "map[0:v0 ...+99]", fmt.Sprintf("%v", m))
+
+	m.SetFormatLimit(-1)
+	assert.Equal(t, 100, strings.Count(fmt.Sprintf("%v", m), ":"))
+}
+
+func TestLogValue(t *testing.T) {
+	t.Parallel()
+	m := newFormatTestMap(3)
+	m.EnableStats()
+	m.Get(1)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+	logger.Info("state", "map", m)
+	assert.Equal(t, "level=INFO msg=state map.len=3 map.index_size=8 map.fill_rate=12 map.resizes=0 map.hits=1 map.misses=0\n",
+		buf.String())
+}
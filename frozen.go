@@ -0,0 +1,234 @@
+package hashmap
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	// frozenBucketSize is the average number of keys per bucket of the perfect hash function.
+	frozenBucketSize = 3
+	// frozenMaxSeed is the maximum seed that is tried to place a bucket before its keys
+	// are moved to the overflow map.
+	frozenMaxSeed = 1 << 20
+	// frozenDirectSlot marks a seed that directly contains the slot of a single key bucket.
+	frozenDirectSlot = 1 << 31
+)
+
+// FrozenMap is an immutable read-only map that is created by Map.Freeze. It uses a minimal
+// perfect hash function built using the hash and displace algorithm, every lookup
+// accesses a single slot and does not use atomic operations.
+type FrozenMap[Key hashable, Value any] struct {
+	hasher   func(Key) uintptr
+	seeds    []uint32 // seed of the slot hash function for every bucket
+	keys     []Key
+	values   []Value
+	overflow map[Key]Value // keys that have the same hash as another key or could not be placed
+	empty    []bool        // marks unused slots, nil if all slots are used
+	holes    int           // number of unused slots
+}
+
+// frozenEntry is an element of a map that gets frozen.
+type frozenEntry[Key hashable, Value any] struct {
+	hash  uint64
+	key   Key
+	value Value
+}
+
+// Freeze returns an immutable copy of the current elements of the map that is optimized for
+// reading. Elements that are modified concurrently may or may not be included.
+func (m *Map[Key, Value]) Freeze() *FrozenMap[Key, Value] {
+	fm := &FrozenMap[Key, Value]{
+		hasher: m.hasher,
+	}
+
+	entries := make([]frozenEntry[Key, Value], 0, m.Len())
+	var lastHash uint64
+	m.Range(func(key Key, value Value) bool {
+		hash := uint64(m.hasher(key))
+		if len(entries) > 0 && hash == lastHash { // the list is sorted by hash
+			fm.addOverflow(key, value) // keys with equal hashes can not be separated
+			return true
+		}
+		lastHash = hash
+		entries = append(entries, frozenEntry[Key, Value]{hash: hash, key: key, value: value})
+		return true
+	})
+
+	fm.build(entries)
+	return fm
+}
+
+// build creates the perfect hash function for the entries.
+func (fm *FrozenMap[Key, Value]) build(entries []frozenEntry[Key, Value]) {
+	count := uint64(len(entries))
+	fm.seeds = make([]uint32, count/frozenBucketSize+1)
+	fm.keys = make([]Key, count)
+	fm.values = make([]Value, count)
+
+	buckets := make([][]int, len(fm.seeds))
+	for i, entry := range entries {
+		bucket := fm.bucket(entry.hash)
+		buckets[bucket] = append(buckets[bucket], i)
+	}
+
+	order := make([]int, len(buckets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { // place the biggest buckets first
+		return len(buckets[order[i]]) > len(buckets[order[j]])
+	})
+
+	used := make([]bool, count)
+	slots := make([]uint64, 0, frozenBucketSize*4)
+	nextFree := uint64(0)
+
+	for _, bucket := range order {
+		indexes := buckets[bucket]
+		switch len(indexes) {
+		case 0:
+			continue
+
+		case 1: // place single keys directly into the remaining free slots
+			for used[nextFree] {
+				nextFree++
+			}
+			used[nextFree] = true
+			fm.seeds[bucket] = frozenDirectSlot | uint32(nextFree)
+			fm.keys[nextFree] = entries[indexes[0]].key
+			fm.values[nextFree] = entries[indexes[0]].value
+			continue
+		}
+
+		seed, ok := fm.findSeed(entries, indexes, used, slots)
+		if !ok {
+			for _, i := range indexes {
+				fm.addOverflow(entries[i].key, entries[i].value)
+			}
+			continue
+		}
+
+		fm.seeds[bucket] = seed
+		for _, i := range indexes {
+			slot := fm.slot(entries[i].hash, seed)
+			used[slot] = true
+			fm.keys[slot] = entries[i].key
+			fm.values[slot] = entries[i].value
+		}
+	}
+
+	// slots of keys that were moved to the overflow map stay empty
+	for slot, isUsed := range used {
+		if isUsed {
+			continue
+		}
+		if fm.empty == nil {
+			fm.empty = make([]bool, count)
+		}
+		fm.empty[slot] = true
+		fm.holes++
+	}
+}
+
+// findSeed searches a seed that maps all keys of a bucket to distinct free slots.
+func (fm *FrozenMap[Key, Value]) findSeed(entries []frozenEntry[Key, Value], indexes []int, used []bool, slots []uint64) (uint32, bool) {
+	for seed := uint32(1); seed < frozenMaxSeed; seed++ {
+		slots = slots[:0]
+		for _, i := range indexes {
+			slot := fm.slot(entries[i].hash, seed)
+			if used[slot] || containsSlot(slots, slot) {
+				break
+			}
+			slots = append(slots, slot)
+		}
+		if len(slots) == len(indexes) {
+			return seed, true
+		}
+	}
+	return 0, false
+}
+
+func containsSlot(slots []uint64, slot uint64) bool {
+	for _, s := range slots {
+		if s == slot {
+			return true
+		}
+	}
+	return false
+}
+
+func (fm *FrozenMap[Key, Value]) addOverflow(key Key, value Value) {
+	if fm.overflow == nil {
+		fm.overflow = map[Key]Value{}
+	}
+	fm.overflow[key] = value
+}
+
+// bucket returns the bucket of a hash.
+func (fm *FrozenMap[Key, Value]) bucket(hash uint64) uint64 {
+	hi, _ := bits.Mul64(mixSeed(hash, 0), uint64(len(fm.seeds)))
+	return hi
+}
+
+// slot returns the slot of a hash for the given seed.
+func (fm *FrozenMap[Key, Value]) slot(hash uint64, seed uint32) uint64 {
+	hi, _ := bits.Mul64(mixSeed(hash, seed), uint64(len(fm.keys)))
+	return hi
+}
+
+// mixSeed mixes the seed into the hash using the xxhash avalanche steps.
+func mixSeed(hash uint64, seed uint32) uint64 {
+	hash ^= uint64(seed) * prime1
+	hash ^= hash >> 33
+	hash *= prime2
+	hash ^= hash >> 29
+	hash *= prime3
+	hash ^= hash >> 32
+	return hash
+}
+
+// Len returns the number of elements within the map.
+func (fm *FrozenMap[Key, Value]) Len() int {
+	return len(fm.keys) - fm.holes + len(fm.overflow)
+}
+
+// Get retrieves an element from the map under given key.
+func (fm *FrozenMap[Key, Value]) Get(key Key) (Value, bool) {
+	if len(fm.keys) > 0 {
+		hash := uint64(fm.hasher(key))
+		seed := fm.seeds[fm.bucket(hash)]
+
+		slot := uint64(seed &^ frozenDirectSlot)
+		if seed&frozenDirectSlot == 0 {
+			slot = fm.slot(hash, seed)
+		}
+		if fm.keys[slot] == key && (fm.empty == nil || !fm.empty[slot]) {
+			return fm.values[slot], true
+		}
+	}
+
+	if fm.overflow != nil {
+		value, ok := fm.overflow[key]
+		return value, ok
+	}
+	return *new(Value), false
+}
+
+// Range calls f sequentially for each key and value present in the map.
+// If f returns false, range stops the iteration.
+func (fm *FrozenMap[Key, Value]) Range(f func(Key, Value) bool) {
+	for slot := range fm.keys {
+		if fm.empty != nil && fm.empty[slot] {
+			continue
+		}
+		if !f(fm.keys[slot], fm.values[slot]) {
+			return
+		}
+	}
+	for key, value := range fm.overflow {
+		if !f(key, value) {
+			return
+		}
+	}
+}
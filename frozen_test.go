@@ -0,0 +1,69 @@
+package hashmap
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestFreeze(t *testing.T) {
+	t.Parallel()
+	m := New[string, int]()
+	itemCount := 1000
+	for i := range itemCount {
+		m.Set(strconv.Itoa(i), i)
+	}
+
+	fm := m.Freeze()
+	m.Set("later", 1) // changes after freezing are not visible
+	assert.Equal(t, itemCount, fm.Len())
+	assert.True(t, fm.overflow == nil)
+	assert.True(t, fm.empty == nil)
+
+	for i := range itemCount {
+		value, ok := fm.Get(strconv.Itoa(i))
+		assert.True(t, ok)
+		assert.Equal(t, i, value)
+	}
+	_, ok := fm.Get("later")
+	assert.False(t, ok)
+
+	count := 0
+	fm.Range(func(key string, value int) bool {
+		assert.Equal(t, strconv.Itoa(value), key)
+		count++
+		return true
+	})
+	assert.Equal(t, itemCount, count)
+}
+
+func TestFreezeEmpty(t *testing.T) {
+	t.Parallel()
+	fm := New[int, int]().Freeze()
+	assert.Equal(t, 0, fm.Len())
+	_, ok := fm.Get(0)
+	assert.False(t, ok)
+}
+
+func TestFreezeHashCollisions(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.SetHasher(func(key int) uintptr {
+		return uintptr(key % 8)
+	})
+	for i := range 32 {
+		m.Set(i, i)
+	}
+
+	fm := m.Freeze()
+	assert.Equal(t, 32, fm.Len())
+	assert.Equal(t, 24, len(fm.overflow))
+	for i := range 32 {
+		value, ok := fm.Get(i)
+		assert.True(t, ok)
+		assert.Equal(t, i, value)
+	}
+	_, ok := fm.Get(32)
+	assert.False(t, ok)
+}
@@ -4,9 +4,8 @@ package hashmap
 import (
 	"bytes"
 	"fmt"
-	"reflect"
-	"strconv"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -18,6 +17,28 @@ type Map[Key hashable, Value any] struct {
 	// resizing marks a resizing operation in progress.
 	// this is using uintptr instead of atomic.Bool to avoid using 32 bit int on 64 bit systems
 	resizing atomic.Uintptr
+	ttl      int64 // sliding expiration duration in nanoseconds, 0 if disabled
+
+	customHasher bool             // marks that the hasher was set using SetHasher
+	versioned    atomic.Uintptr   // marks that changes of the map increase the version
+	version      atomic.Uint64    // modification counter that gets increased by every change of the map
+	tombstones   *tombstones[Key] // deleted keys retained for delta snapshots, nil if disabled
+
+	sizer       func(Key, Value) int64 // returns the weight of an element, nil if the memory budget is disabled
+	budget      int64                  // maximum total weight of all elements
+	weight      atomic.Int64           // total weight of all elements
+	evictions   atomic.Uint64          // number of elements evicted to stay within the budget
+	evicting    atomic.Uintptr         // marks an eviction in progress
+	evictCursor atomic.Uintptr         // hash key to continue evicting elements at
+
+	valueCodec  ValueCodec[Value] // codec for the binary serialization of values, nil for the default
+	counters    *counters         // operation counters, nil if disabled
+	resizes     atomic.Uint64     // number of replacements of the index by a resized one
+	onResize    func(ResizeEvent) // called after every resize, nil if not set
+	formatLimit int               // maximum number of entries printed by Format, 0 for the default
+
+	resizeStore atomic.Pointer[store[Key, Value]] // second index that is referenced while resizing
+	tracing     *tracing                          // tracer of sampled operations, nil if disabled
 }
 
 // New returns a new map instance.
@@ -36,6 +57,7 @@ func NewSized[Key hashable, Value any](size uintptr) *Map[Key, Value] {
 // SetHasher sets a custom hasher.
 func (m *Map[Key, Value]) SetHasher(hasher func(Key) uintptr) {
 	m.hasher = hasher
+	m.customHasher = true
 }
 
 // Len returns the number of elements within the map.
@@ -43,20 +65,57 @@ func (m *Map[Key, Value]) Len() int {
 	return m.linkedList.Len()
 }
 
+// Version returns the modification counter of the map, it increases with every change of the map.
+// The counter is only maintained once a Snapshotter is attached to the map or
+// SetTombstoneRetention is called, until then it is 0.
+func (m *Map[Key, Value]) Version() uint64 {
+	return m.version.Load()
+}
+
+// enableVersioning enables the modification counter of the map.
+func (m *Map[Key, Value]) enableVersioning() {
+	m.versioned.Store(1)
+}
+
+// increaseVersion increases the modification counter if it is enabled.
+func (m *Map[Key, Value]) increaseVersion() {
+	if m.versioned.Load() != 0 {
+		m.version.Add(1)
+	}
+}
+
 // Get retrieves an element from the map under given hash key.
 func (m *Map[Key, Value]) Get(key Key) (Value, bool) {
 	hash := m.hasher(key)
+	if m.tracing != nil && m.tracing.sample() {
+		return m.traceGet(hash, key)
+	}
 
+	value, ok, _ := m.get(hash, key)
+	return value, ok
+}
+
+// get returns the value for the key and the number of list elements that were walked.
+func (m *Map[Key, Value]) get(hash uintptr, key Key) (Value, bool, int) {
+	walked := 0
 	for element := m.store.Load().item(hash); element != nil; element = element.Next() {
+		walked++
 		if element.keyHash == hash && element.key == key {
-			return element.Value(), true
+			if m.ttl != 0 && !m.touch(element, nanotime()) {
+				m.counters.miss(hash)
+				return *new(Value), false, walked
+			}
+			m.counters.hit(hash)
+			return element.Value(), true, walked
 		}
 
 		if element.keyHash > hash {
-			return *new(Value), false
+			m.counters.miss(hash)
+			return *new(Value), false, walked
 		}
 	}
-	return *new(Value), false
+	m.counters.miss(hash)
+	return *new(Value), false, walked
 }
 
 // GetOrInsert returns the existing value for the key if present.
@@ -76,22 +135,40 @@ func (m *Map[Key, Value]) GetOrInsert(key Key, value Value) (Value, bool) {
 		if !inserted { // if retrying after insert during grow, do not add to list again
 			element, existed, inserted = m.linkedList.Add(searchStart, hash, key, value)
 			if existed {
+				if m.ttl != 0 && !m.touch(element, nanotime()) {
+					m.removeElement(element)
+					continue
+				}
+				m.counters.hit(hash)
 				return element.Value(), true
 			}
 			if !inserted {
+				m.counters.casRetry(hash)
 				continue // a concurrent add did interfere, try again
 			}
+			if m.ttl != 0 {
+				element.meta().deadline.Store(nanotime() + m.ttl)
+			}
+			if m.sizer != nil {
+				m.setWeight(element, m.sizer(key, value))
+			}
+			m.stampVersion(element)
 		}
 
 		count := store.addItem(element)
 		currentStore := m.store.Load()
 		if store != currentStore { // retry insert in case of insert during grow
+			m.counters.casRetry(hash)
 			continue
 		}
 
 		if m.isResizeNeeded(store, count) && m.resizing.CompareAndSwap(0, 1) {
 			go m.grow(0, true)
 		}
+		if m.sizer != nil {
+			m.evictOverBudget(element)
+		}
+		m.counters.insert(hash)
 		return value, false
 	}
 }
@@ -107,21 +184,32 @@ func (m *Map[Key, Value]) FillRate() int {
 // Del deletes the key from the map and returns whether the key was deleted.
 func (m *Map[Key, Value]) Del(key Key) bool {
 	hash := m.hasher(key)
-	store := m.store.Load()
-	element := store.item(hash)
+	if m.tracing != nil && m.tracing.sample() {
+		return m.traceDel(hash, key)
+	}
+
+	deleted, _ := m.del(hash, key)
+	return deleted
+}
 
-	for ; element != nil; element = element.Next() {
+// del deletes the key and returns whether it was deleted and the number of list elements that were walked.
+func (m *Map[Key, Value]) del(hash uintptr, key Key) (bool, int) {
+	walked := 0
+	for element := m.store.Load().item(hash); element != nil; element = element.Next() {
+		walked++
 		if element.keyHash == hash && element.key == key {
-			m.deleteElement(element)
-			m.linkedList.Delete(element)
-			return true
+			if !m.removeElement(element) {
+				return false, walked // deleted concurrently
+			}
+			m.counters.delete(hash)
+			return true, walked
 		}
 
 		if element.keyHash > hash {
-			return false
+			return false, walked
 		}
 	}
-	return false
+	return false, walked
 }
 
 // Insert sets the value under the specified key to the map if it does not exist yet.
@@ -142,22 +230,39 @@ func (m *Map[Key, Value]) Insert(key Key, value Value) bool {
 		if !inserted { // if retrying after insert during grow, do not add to list again
 			element, existed, inserted = m.linkedList.Add(searchStart, hash, key, value)
 			if existed {
+				if m.ttl != 0 && m.isExpired(element, nanotime()) {
+					m.removeElement(element)
+					continue
+				}
 				return false
 			}
 			if !inserted {
+				m.counters.casRetry(hash)
 				continue // a concurrent add did interfere, try again
 			}
+			if m.ttl != 0 {
+				element.meta().deadline.Store(nanotime() + m.ttl)
+			}
+			if m.sizer != nil {
+				m.setWeight(element, m.sizer(key, value))
+			}
+			m.stampVersion(element)
 		}
 
 		count := store.addItem(element)
 		currentStore := m.store.Load()
 		if store != currentStore { // retry insert in case of insert during grow
+			m.counters.casRetry(hash)
 			continue
 		}
 
 		if m.isResizeNeeded(store, count) && m.resizing.CompareAndSwap(0, 1) {
 			go m.grow(0, true)
 		}
+		if m.sizer != nil {
+			m.evictOverBudget(element)
+		}
+		m.counters.insert(hash)
 		return true
 	}
 }
@@ -167,26 +272,57 @@ func (m *Map[Key, Value]) Insert(key Key, value Value) bool {
 // after the resize operation is finished.
 func (m *Map[Key, Value]) Set(key Key, value Value) {
 	hash := m.hasher(key)
+	if m.tracing != nil && m.tracing.sample() {
+		m.traceSet(hash, key, value)
+		return
+	}
+	m.set(hash, key, value)
+}
 
-	for {
+// set sets the value under the key and returns the number of retries due to concurrent changes
+// and the number of list elements that were walked by all attempts.
+func (m *Map[Key, Value]) set(hash uintptr, key Key, value Value) (retries, walked int) {
+	inserted := false // a retry after a grow updates the element that this call inserted
+
+	for ; ; retries++ {
 		store := m.store.Load()
 		searchStart := store.item(hash)
 
-		element, added := m.linkedList.AddOrUpdate(searchStart, hash, key, value)
+		element, updated, added, searched := m.linkedList.addOrUpdate(searchStart, hash, key, value)
+		walked += searched
 		if !added {
+			m.counters.casRetry(hash)
 			continue // a concurrent add did interfere, try again
 		}
+		inserted = inserted || !updated
+		if m.ttl != 0 && !m.touch(element, nanotime()) {
+			m.removeElement(element) // an expired element got updated, replace it by a new one
+			continue
+		}
+		if m.sizer != nil {
+			m.setWeight(element, m.sizer(key, value))
+		}
+		m.stampVersion(element)
 
 		count := store.addItem(element)
 		currentStore := m.store.Load()
 		if store != currentStore { // retry insert in case of insert during grow
+			m.counters.casRetry(hash)
 			continue
 		}
 
 		if m.isResizeNeeded(store, count) && m.resizing.CompareAndSwap(0, 1) {
 			go m.grow(0, true)
 		}
-		return
+		if m.sizer != nil {
+			m.evictOverBudget(element)
+		}
+		if inserted {
+			m.counters.insert(hash)
+		} else {
+			m.counters.update(hash)
+		}
+		return retries, walked
 	}
 }
 
@@ -224,7 +360,17 @@ func (m *Map[Key, Value]) String() string {
 func (m *Map[Key, Value]) Range(f func(Key, Value) bool) {
 	item := m.linkedList.First()
 
+	var now int64
+	if m.ttl != 0 {
+		now = nanotime()
+	}
+
 	for item != nil {
+		if m.ttl != 0 && m.isExpired(item, now) {
+			item = item.Next()
+			continue
+		}
+
 		value := item.Value()
 		if !f(item.key, value) {
 			return
@@ -235,6 +381,7 @@ func (m *Map[Key, Value]) Range(f func(Key, Value) bool) {
 
 func (m *Map[Key, Value]) allocate(newSize uintptr) {
 	m.linkedList = NewList[Key, Value]()
+	m.enableElementMeta()
 	if m.resizing.CompareAndSwap(0, 1) {
 		m.grow(newSize, false)
 	}
@@ -246,6 +393,26 @@ func (m *Map[Key, Value]) isResizeNeeded(store *store[Key, Value], count uintptr
 	return fillRate > maxFillRate
 }
 
+// removeElement removes an element from the index and the list and returns whether this
+// call removed it. The removal is only recorded by the call that deleted the element from
+// the list, concurrent removals of the same element do not add tombstones or release its
+// weight again.
+func (m *Map[Key, Value]) removeElement(element *ListElement[Key, Value]) bool {
+	m.deleteElement(element)
+	if !m.linkedList.Delete(element) {
+		return false
+	}
+	if m.tombstones == nil {
+		m.increaseVersion()
+	} else {
+		m.tombstones.add(element.keyHash, element.key, &m.version)
+	}
+	if m.sizer != nil {
+		m.weight.Add(-element.meta().weight.Swap(0))
+	}
+	return true
+}
+
 // deleteElement deletes an element from index.
 func (m *Map[Key, Value]) deleteElement(element *ListElement[Key, Value]) {
 	for {
@@ -254,10 +421,12 @@ func (m *Map[Key, Value]) deleteElement(element *ListElement[Key, Value]) {
 		ptr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(store.array) + index*intSizeBytes))
 
 		next := element.Next()
-		if next != nil && element.keyHash>>store.keyShifts != index {
+		if next != nil && next.keyHash>>store.keyShifts != index {
 			next = nil // do not set index to next item if it's not the same slice index
 		}
-		atomic.CompareAndSwapPointer(ptr, unsafe.Pointer(element), unsafe.Pointer(next))
+		if atomic.CompareAndSwapPointer(ptr, unsafe.Pointer(element), unsafe.Pointer(next)) && next == nil {
+			store.count.Add(^uintptr(0)) // the slot is empty now
+		}
 
 		currentStore := m.store.Load()
 		if store == currentStore { // check that no resize happened
@@ -277,28 +446,47 @@ func (m *Map[Key, Value]) grow(newSize uintptr, loop bool) {
 			newSize = roundUpPower2(newSize)
 		}
 
-		index := make([]*ListElement[Key, Value], newSize)
-		header := (*reflect.SliceHeader)(unsafe.Pointer(&index))
-
-		newStore := &store[Key, Value]{
-			keyShifts: strconv.IntSize - log2(newSize),
-			array:     unsafe.Pointer(header.Data), // use address of slice data storage
-			index:     index,
-		}
+		started := time.Now()
+		newStore := makeStore[Key, Value](newSize)
+		m.resizeStore.Store(newStore)
 
 		m.fillIndexItems(newStore) // initialize new index slice with longer keys
+		filled := time.Now()
 
 		m.store.Store(newStore)
+		m.resizeStore.Store(currentStore) // operations that started before can still use it
+		if currentStore != nil {          // do not count the initial allocation
+			m.resizes.Add(1)
+		}
 
 		m.fillIndexItems(newStore) // make sure that the new index is up-to-date with the current state of the linked list
+		finished := time.Now()
+		m.resizeStore.Store(nil)
 
-		if !loop {
-			return
+		// check if a new resize needs to be done already
+		count := m.Len()
+		followUp := loop && m.isResizeNeeded(newStore, uintptr(count))
+
+		if m.onResize != nil && currentStore != nil {
+			m.onResize(ResizeEvent{
+				OldSize:    len(currentStore.index),
+				NewSize:    len(newStore.index),
+				Count:      count,
+				FirstFill:  filled.Sub(started),
+				SecondFill: finished.Sub(filled),
+				FollowUp:   followUp,
+			})
 		}
 
-		// check if a new resize needs to be done already
-		count := uintptr(m.Len())
-		if !m.isResizeNeeded(newStore, count) {
+		if m.tracing != nil && currentStore != nil && m.tracing.sample() {
+			m.tracing.tracer.Trace(TraceEvent{
+				Op:       OpResize,
+				Walked:   count,
+				Duration: finished.Sub(started),
+			})
+		}
+
+		if !followUp {
 			return
 		}
 		newSize = 0 // 0 means double the current size
@@ -319,3 +507,16 @@ func (m *Map[Key, Value]) fillIndexItems(store *store[Key, Value]) {
 		item = item.Next()
 	}
 }
+
+// needsElementMeta returns whether an enabled feature of the map keeps element metadata.
+func (m *Map[Key, Value]) needsElementMeta() bool {
+	return m.ttl != 0 || m.sizer != nil || m.tombstones != nil
+}
+
+// enableElementMeta makes the list of the map keep element metadata if an enabled feature
+// needs it. It is called by the setters of these features before the map is used.
+func (m *Map[Key, Value]) enableElementMeta() {
+	if m.linkedList != nil && m.needsElementMeta() {
+		m.linkedList.keepMeta()
+	}
+}
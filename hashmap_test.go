@@ -449,7 +449,7 @@ func TestConcurrentInsertDelete(t *testing.T) {
 		wg.Wait()
 
 		assert.Equal(t, 3, l.Len())
-		_, found, _ := l.search(nil, newIl.keyHash, newIl.key)
+		_, found, _, _ := l.search(nil, newIl.keyHash, newIl.key)
 		assert.True(t, found != nil)
 	}
 }
@@ -488,3 +488,24 @@ func TestGetOrInsertHangIssue67(_ *testing.T) {
 
 	wg.Wait()
 }
+
+func TestDeleteFillRate(t *testing.T) {
+	t.Parallel()
+	m := NewSized[uintptr, uintptr](1024)
+	for i := uintptr(0); i < 100; i++ {
+		m.Set(i, i)
+	}
+	assert.True(t, m.FillRate() > 0)
+
+	for i := uintptr(0); i < 100; i++ {
+		assert.True(t, m.Del(i))
+	}
+	assert.Equal(t, 0, m.FillRate())
+
+	store := m.store.Load()
+	for i, item := range store.index {
+		if item != nil {
+			t.Fatalf("index slot %d still references an element after all deletes", i)
+		}
+	}
+}
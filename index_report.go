@@ -0,0 +1,62 @@
+package hashmap
+
+// IndexReport describes how the elements of a map are distributed over the slots of its index.
+type IndexReport struct {
+	IndexSize  int     // number of slots of the index
+	Elements   int     // number of elements in the list
+	EmptySlots int     // number of slots that no element hashes to
+	EmptyRatio float64 // ratio of empty slots to all slots
+	MaxRun     int     // highest number of elements that hash to the same slot
+	// AvgProbe is the average number of list elements that a lookup of an existing key
+	// visits, starting at the element that the index slot points to.
+	AvgProbe float64
+	// Histogram contains the number of slots per count of elements that hash to a slot,
+	// Histogram[0] is the number of empty slots.
+	Histogram []int
+}
+
+// IndexReport walks the index and the list of the map and reports the distribution of the
+// elements. A high maximum run or average probe length indicates a bad hasher or a too
+// small index. The report is not consistent if the map is modified concurrently.
+func (m *Map[Key, Value]) IndexReport() IndexReport {
+	store := m.store.Load()
+	report := IndexReport{
+		IndexSize: len(store.index),
+		Histogram: []int{len(store.index)},
+	}
+
+	var probes, run int
+	slot := uintptr(0)
+	for item := m.linkedList.First(); item != nil; item = item.Next() {
+		index := item.keyHash >> store.keyShifts
+		if run == 0 || index != slot {
+			report.addRun(run)
+			slot = index
+			run = 0
+		}
+		run++
+		probes += run // the first element of a slot is found directly through the index
+		report.Elements++
+	}
+	report.addRun(run)
+
+	report.EmptySlots = report.Histogram[0]
+	report.EmptyRatio = float64(report.EmptySlots) / float64(report.IndexSize)
+	if report.Elements > 0 {
+		report.AvgProbe = float64(probes) / float64(report.Elements)
+	}
+	return report
+}
+
+// addRun adds a slot with the given number of elements to the histogram.
+func (r *IndexReport) addRun(run int) {
+	if run == 0 {
+		return
+	}
+	for len(r.Histogram) <= run {
+		r.Histogram = append(r.Histogram, 0)
+	}
+	r.Histogram[run]++
+	r.Histogram[0]--
+	r.MaxRun = max(r.MaxRun, run)
+}
@@ -0,0 +1,55 @@
+package hashmap
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestIndexReport(t *testing.T) {
+	t.Parallel()
+	m := NewSized[uintptr, int](8)
+	m.SetHasher(func(key uintptr) uintptr {
+		return key << (strconv.IntSize - 3) // use the key as index slot
+	})
+	m.Set(0, 0)
+	m.Set(1, 1)
+	m.Set(3, 3)
+
+	report := m.IndexReport()
+	assert.Equal(t, 8, report.IndexSize)
+	assert.Equal(t, 3, report.Elements)
+	assert.Equal(t, 5, report.EmptySlots)
+	assert.Equal(t, 1, report.MaxRun)
+	assert.Equal(t, 1.0, report.AvgProbe)
+	assert.Equal(t, []int{5, 3}, report.Histogram)
+}
+
+func TestIndexReportCollisions(t *testing.T) {
+	t.Parallel()
+	m := NewSized[int, int](8)
+	m.SetHasher(func(key int) uintptr {
+		return uintptr(key) // all small keys hash to the first slot
+	})
+	for i := range 4 {
+		m.Set(i, i)
+	}
+
+	report := m.IndexReport()
+	assert.Equal(t, 4, report.Elements)
+	assert.Equal(t, 7, report.EmptySlots)
+	assert.Equal(t, 7.0/8, report.EmptyRatio)
+	assert.Equal(t, 4, report.MaxRun)
+	assert.Equal(t, 2.5, report.AvgProbe)
+	assert.Equal(t, []int{7, 0, 0, 0, 1}, report.Histogram)
+}
+
+func TestIndexReportEmpty(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	report := m.IndexReport()
+	assert.Equal(t, 0, report.Elements)
+	assert.Equal(t, 1.0, report.EmptyRatio)
+	assert.Equal(t, 0.0, report.AvgProbe)
+}
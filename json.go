@@ -0,0 +1,167 @@
+package hashmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var errJSONObjectExpected = errors.New("json object expected")
+
+// MarshalJSON returns the map encoded as JSON object, keys are encoded as JSON strings
+// like encoding/json does for Go maps. The elements are written in the order of their
+// hashed keys without building an intermediate Go map.
+func (m *Map[Key, Value]) MarshalJSON() ([]byte, error) {
+	if m.linkedList == nil { // zero value map
+		return []byte("{}"), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	var err error
+	first := true
+	m.Range(func(key Key, value Value) bool {
+		var encoded []byte
+		encoded, err = json.Marshal(value)
+		if err != nil {
+			err = fmt.Errorf("encoding value of key %v: %w", key, err)
+			return false
+		}
+
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		keyString, _ := json.Marshal(formatKey(key)) // encoding a string can not fail
+		buf.Write(keyString)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON adds all elements of the JSON object to the map, existing elements are kept.
+// The index of the map is sized once for the number of elements before they get inserted.
+// A zero value map gets initialized.
+func (m *Map[Key, Value]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	type entry struct {
+		key   Key
+		value json.RawMessage
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("decoding json: %w", err)
+	}
+	if token != json.Delim('{') {
+		return errJSONObjectExpected
+	}
+
+	var entries []entry
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return fmt.Errorf("decoding json: %w", err)
+		}
+		s, _ := token.(string) // object keys are always strings
+		key, err := parseKey[Key](s)
+		if err != nil {
+			return err
+		}
+
+		var value json.RawMessage
+		if err = decoder.Decode(&value); err != nil {
+			return fmt.Errorf("decoding json: %w", err)
+		}
+		entries = append(entries, entry{key: key, value: value})
+	}
+
+	m.reserve(len(entries))
+	for _, e := range entries {
+		var value Value
+		if err := json.Unmarshal(e.value, &value); err != nil {
+			return fmt.Errorf("decoding value of key %v: %w", e.key, err)
+		}
+		m.Set(e.key, value)
+	}
+	return nil
+}
+
+// reserve initializes a zero value map and grows the index of the map synchronously
+// to be able to hold the given number of additional elements.
+func (m *Map[Key, Value]) reserve(count int) {
+	if m.linkedList == nil {
+		m.allocate(indexSizeFor(count))
+		m.setDefaultHasher()
+		return
+	}
+
+	size := indexSizeFor(m.Len() + count)
+	if size > uintptr(len(m.store.Load().index)) && m.resizing.CompareAndSwap(0, 1) {
+		m.grow(size, false)
+	}
+}
+
+// formatKey returns the key formatted as string.
+func formatKey[Key hashable](key Key) string {
+	v := reflect.ValueOf(key)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return v.String()
+	}
+}
+
+// parseKey parses a key that was formatted by formatKey.
+func parseKey[Key hashable](s string) (Key, error) {
+	var key Key
+	v := reflect.ValueOf(&key).Elem()
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return key, fmt.Errorf("parsing key '%s': %w", s, err)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return key, fmt.Errorf("parsing key '%s': %w", s, err)
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return key, fmt.Errorf("parsing key '%s': %w", s, err)
+		}
+		v.SetFloat(f)
+	default:
+		v.SetString(s)
+	}
+	return key, nil
+}
@@ -0,0 +1,99 @@
+package hashmap
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	t.Parallel()
+	m := New[string, []int]()
+	data, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	m.Set("a", []int{1, 2})
+	m.Set("b\"", nil)
+	data, err = json.Marshal(m)
+	assert.NoError(t, err)
+
+	var expected map[string][]int
+	assert.NoError(t, json.Unmarshal(data, &expected))
+	assert.Equal(t, map[string][]int{"a": {1, 2}, "b\"": nil}, expected)
+}
+
+// jsonPayload is a struct for testing that embeds a map by value.
+type jsonPayload struct {
+	Items Map[string, int] `json:"items"`
+}
+
+func TestJSONZeroValue(t *testing.T) {
+	t.Parallel()
+	var payload jsonPayload
+	data, err := json.Marshal(&payload)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"items":{}}`, string(data))
+
+	var decoded jsonPayload
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, 0, decoded.Items.Len())
+
+	decoded.Items.Set("a", 1)
+	data, err = json.Marshal(&decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"items":{"a":1}}`, string(data))
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+	data := []byte(`{"1": "one", "-2": "minus two", "3": "three"}`)
+
+	var m *Map[int8, string]
+	assert.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, 3, m.Len())
+	value, ok := m.Get(-2)
+	assert.True(t, ok)
+	assert.Equal(t, "minus two", value)
+
+	existing := New[int8, string]()
+	existing.Set(4, "four")
+	assert.NoError(t, json.Unmarshal(data, existing))
+	assert.Equal(t, 4, existing.Len())
+
+	assert.True(t, json.Unmarshal([]byte(`{"1000": "overflow"}`), existing) != nil)
+	assert.True(t, json.Unmarshal([]byte(`[]`), existing) != nil)
+}
+
+func TestJSONRoundTripFloat(t *testing.T) {
+	t.Parallel()
+	m := New[float64, bool]()
+	m.Set(1.5, true)
+	m.Set(-0.25, false)
+
+	data, err := json.Marshal(m)
+	assert.NoError(t, err)
+
+	decoded := New[float64, bool]()
+	assert.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, 2, decoded.Len())
+	value, ok := decoded.Get(1.5)
+	assert.True(t, ok)
+	assert.True(t, value)
+}
+
+func TestUnmarshalJSONPresized(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	values := map[int]int{}
+	for i := range 1000 {
+		values[i] = i
+	}
+	data, err := json.Marshal(values)
+	assert.NoError(t, err)
+
+	assert.NoError(t, json.Unmarshal(data, m))
+	assert.Equal(t, 1000, m.Len())
+	assert.Equal(t, indexSizeFor(1000), len(m.store.Load().index))
+}
@@ -8,6 +8,7 @@ import (
 type List[Key comparable, Value any] struct {
 	count atomic.Uintptr
 	head  *ListElement[Key, Value]
+	boxed bool // marks that values are stored in boxes that reference element metadata
 }
 
 // NewList returns an initialized list.
@@ -30,7 +31,7 @@ func (l *List[Key, Value]) First() *ListElement[Key, Value] {
 // Add adds an item to the list and returns false if an item for the hash existed.
 // searchStart = nil will start to search at the head item.
 func (l *List[Key, Value]) Add(searchStart *ListElement[Key, Value], hash uintptr, key Key, value Value) (element *ListElement[Key, Value], existed bool, inserted bool) {
-	left, found, right := l.search(searchStart, hash, key)
+	left, found, right, _ := l.search(searchStart, hash, key)
 	if found != nil { // existing item found
 		return found, true, false
 	}
@@ -39,30 +40,67 @@ func (l *List[Key, Value]) Add(searchStart *ListElement[Key, Value], hash uintpt
 		key:     key,
 		keyHash: hash,
 	}
-	element.value.Store(&value)
+	element.value.Store(l.newValue(value, nil))
 	return element, false, l.insertAt(element, left, right)
 }
 
 // AddOrUpdate adds or updates an item to the list.
 func (l *List[Key, Value]) AddOrUpdate(searchStart *ListElement[Key, Value], hash uintptr, key Key, value Value) (*ListElement[Key, Value], bool) {
-	left, found, right := l.search(searchStart, hash, key)
+	element, _, ok, _ := l.addOrUpdate(searchStart, hash, key, value)
+	return element, ok
+}
+
+// addOrUpdate adds or updates an item to the list and returns whether an existing item was updated
+// and the number of elements that were walked.
+func (l *List[Key, Value]) addOrUpdate(searchStart *ListElement[Key, Value], hash uintptr, key Key, value Value) (element *ListElement[Key, Value], updated bool, ok bool, walked int) {
+	left, found, right, walked := l.search(searchStart, hash, key)
 	if found != nil { // existing item found
-		found.value.Store(&value) // update the value
-		return found, true
+		found.value.Store(l.newValue(value, found)) // update the value
+		return found, true, true, walked
 	}
 
-	element := &ListElement[Key, Value]{
+	element = &ListElement[Key, Value]{
 		key:     key,
 		keyHash: hash,
 	}
-	element.value.Store(&value)
-	return element, l.insertAt(element, left, right)
+	element.value.Store(l.newValue(value, nil))
+	return element, false, l.insertAt(element, left, right), walked
+}
+
+// newValue returns the value pointer to store in an element. If the list keeps element
+// metadata, the value is boxed together with the metadata of the existing element or
+// new metadata for a new element.
+func (l *List[Key, Value]) newValue(value Value, existing *ListElement[Key, Value]) *Value {
+	if !l.boxed {
+		return &value
+	}
+
+	box := &valueBox[Value]{value: value}
+	if existing != nil {
+		box.meta = existing.meta()
+	} else {
+		box.meta = &elementMeta{}
+	}
+	return &box.value
 }
 
-// Delete deletes an element from the list.
-func (l *List[Key, Value]) Delete(element *ListElement[Key, Value]) {
+// keepMeta makes the list keep element metadata, the values of existing elements get boxed.
+// It must not be called concurrently with other operations on the list.
+func (l *List[Key, Value]) keepMeta() {
+	if l.boxed {
+		return
+	}
+	l.boxed = true
+	for element := l.First(); element != nil; element = element.Next() {
+		element.value.Store(l.newValue(element.Value(), nil))
+	}
+}
+
+// Delete deletes an element from the list and returns whether this call deleted it.
+// It returns false if the element got deleted by a concurrent call.
+func (l *List[Key, Value]) Delete(element *ListElement[Key, Value]) bool {
 	if !element.deleted.CompareAndSwap(0, 1) {
-		return // concurrent delete of the item is in progress
+		return false // concurrent delete of the item is in progress
 	}
 
 	right := element.Next()
@@ -73,9 +111,12 @@ func (l *List[Key, Value]) Delete(element *ListElement[Key, Value]) {
 	// pointer to the next valid element on call of Next().
 
 	l.count.Add(^uintptr(0)) // decrease counter
+	return true
 }
 
-func (l *List[Key, Value]) search(searchStart *ListElement[Key, Value], hash uintptr, key Key) (left, found, right *ListElement[Key, Value]) {
+// search returns the elements left and right of the position of the key or the element of
+// the key if it exists, as well as the number of elements that were walked.
+func (l *List[Key, Value]) search(searchStart *ListElement[Key, Value], hash uintptr, key Key) (left, found, right *ListElement[Key, Value], walked int) {
 	if searchStart != nil && hash < searchStart.keyHash { // key would remain left from item? {
 		searchStart = nil // start search at head
 	}
@@ -84,29 +125,30 @@ func (l *List[Key, Value]) search(searchStart *ListElement[Key, Value], hash uin
 		left = l.head
 		found = left.Next()
 		if found == nil { // no items beside head?
-			return nil, nil, nil
+			return nil, nil, nil, 0
 		}
 	} else {
 		found = searchStart
 	}
 
 	for {
+		walked++
 		if hash == found.keyHash && key == found.key { // key hash already exists, compare keys
-			return nil, found, nil
+			return nil, found, nil, walked
 		}
 
 		if hash < found.keyHash { // new item needs to be inserted before the found value
 			if l.head == left {
-				return nil, nil, found
+				return nil, nil, found, walked
 			}
-			return left, nil, found
+			return left, nil, found, walked
 		}
 
 		// go to next element in sorted linked list
 		left = found
 		found = left.Next()
 		if found == nil { // no more items on the right
-			return left, nil, nil
+			return left, nil, nil, walked
 		}
 	}
 }
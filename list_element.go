@@ -2,6 +2,7 @@ package hashmap
 
 import (
 	"sync/atomic"
+	"unsafe"
 )
 
 // ListElement is an element of a list.
@@ -21,6 +22,36 @@ type ListElement[Key comparable, Value any] struct {
 	key Key
 }
 
+// elementMeta is the state of an element that is only kept for optional map features.
+// It is referenced from the value boxes of the element and only allocated if one of the
+// features is enabled, elements of other maps do not pay for it.
+type elementMeta struct {
+	// deadline is the expiration time in nanoseconds when sliding expiration is enabled.
+	// it is 0 for elements that do not expire.
+	deadline atomic.Int64
+
+	// weight is the weight of the element as reported by the sizer of the map.
+	weight atomic.Int64
+
+	// version is the map version of the last change of the element when tombstones are retained.
+	version atomic.Uint64
+}
+
+// valueBox is the allocation that the value pointer of an element points to if the list
+// keeps element metadata. The value is the first field, the value pointer can therefore
+// be read like the one of an element without metadata. All boxes of an element reference
+// the same metadata.
+type valueBox[Value any] struct {
+	value Value
+	meta  *elementMeta
+}
+
+// meta returns the metadata of the element, it must only be called for elements of
+// lists that keep element metadata.
+func (e *ListElement[Key, Value]) meta() *elementMeta {
+	return (*valueBox[Value])(unsafe.Pointer(e.value.Load())).meta
+}
+
 // Value returns the value of the list item.
 func (e *ListElement[Key, Value]) Value() Value {
 	return *e.value.Load()
@@ -2,6 +2,7 @@ package hashmap
 
 import (
 	"testing"
+	"unsafe"
 
 	"github.com/cornelk/hashmap/assert"
 )
@@ -14,3 +15,10 @@ func TestListNew(t *testing.T) {
 	node = l.head.Next()
 	assert.True(t, node == nil)
 }
+
+func TestListElementSize(t *testing.T) {
+	t.Parallel()
+	// the state of optional map features is kept outside of the element, see elementMeta
+	word := unsafe.Sizeof(uintptr(0))
+	assert.Equal(t, 5*word, unsafe.Sizeof(ListElement[uintptr, uintptr]{}))
+}
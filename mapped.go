@@ -0,0 +1,430 @@
+package hashmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+)
+
+// Mapped file format
+//
+// A mapped file is built once from a map and opened read-only using mmap, which allows
+// many processes to share its pages. All integers are stored in little endian byte order.
+//
+//	header:
+//	  magic        [4]byte  "HMMF"
+//	  version      uint16   mappedVersion
+//	  key kind     uint8    reflect.Kind of the key type
+//	  hasher id    uint8    hasherCustom or hasherDefault
+//	  hash bits    uint8    bit size of the hashes
+//	  bucket bits  uint8    log2 of the number of directory buckets
+//	  reserved     uint16
+//	  count        uint64   number of entries
+//	  checksum     uint32   CRC32 (IEEE) of all previous header bytes
+//	  padding      [8]byte
+//	directory      [buckets + 1]uint64  index of the first entry of every bucket
+//	entries        [count]struct{ hash, offset uint64 } sorted by hash
+//	data:
+//	  key          fixed width numeric or uvarint length prefixed string
+//	  value length uvarint
+//	  value        []byte   value encoded by the value codec
+//
+// The bucket of an entry is formed by the top bits of its hash, like the index of a map.
+const (
+	mappedMagic      = "HMMF"
+	mappedVersion    = 1
+	mappedHeaderSize = 32
+	mappedEntrySize  = 16
+)
+
+// ErrHasherRequired is returned by OpenMapped for files that were written by a map with a
+// custom hasher, they have to be opened using OpenMappedWithHasher.
+var ErrHasherRequired = errors.New("mapped file was written with a custom hasher")
+
+// MappedMap is a read-only map that is backed by a memory mapped file created by
+// Map.WriteMappedFile. It uses the same hasher as Map, the hashes of both are compatible.
+type MappedMap[Key hashable, Value any] struct {
+	hasher     func(Key) uintptr
+	keyCodec   primitiveCodec[Key]
+	valueCodec ValueCodec[Value]
+
+	mapping    []byte // memory mapped file
+	data       []byte // data section of the file
+	count      uint64
+	hashShift  uint8 // shift to normalize a hash to 64 bits
+	bucketBits uint8
+	directory  []byte
+	entries    []byte
+}
+
+// WriteMappedFile writes the map to a file that can be opened using OpenMapped.
+// The file is written to a temporary file first that replaces the file at path once complete.
+// The entries and data sections are spooled to temporary files in the same directory while
+// the map is iterated, to not hold a copy of the map contents in memory.
+// Elements that are modified concurrently may or may not be included.
+func (m *Map[Key, Value]) WriteMappedFile(path string) error {
+	dir := filepath.Dir(path)
+	entries, err := newSpoolFile(dir)
+	if err != nil {
+		return err
+	}
+	defer entries.remove()
+	data, err := newSpoolFile(dir)
+	if err != nil {
+		return err
+	}
+	defer data.remove()
+
+	count, err := m.spoolMappedSections(entries, data)
+	if err != nil {
+		return err
+	}
+
+	keyCodec, _ := newPrimitiveCodec[Key]()
+	bucketBits := uint8(log2(roundUpPower2(uintptr(count/2 + 1))))
+	header := make([]byte, 0, mappedHeaderSize)
+	header = append(header, mappedMagic...)
+	header = binary.LittleEndian.AppendUint16(header, mappedVersion)
+	header = append(header, byte(keyCodec.kind), m.hasherID(), strconv.IntSize, bucketBits, 0, 0)
+	header = binary.LittleEndian.AppendUint64(header, count)
+	header = binary.LittleEndian.AppendUint32(header, crc32.ChecksumIEEE(header))
+	header = append(header, make([]byte, mappedHeaderSize-len(header))...)
+
+	return writeFileAtomic(path, func(w io.Writer) (int64, error) {
+		n, err := w.Write(header)
+		written := int64(n)
+		if err != nil {
+			return written, fmt.Errorf("writing mapped file: %w", err)
+		}
+
+		if err := entries.rewind(); err != nil {
+			return written, err
+		}
+		n64, err := writeMappedDirectory(w, entries.reader, count, strconv.IntSize, bucketBits)
+		written += n64
+		if err != nil {
+			return written, err
+		}
+
+		for _, section := range []*spoolFile{entries, data} {
+			if err := section.rewind(); err != nil {
+				return written, err
+			}
+			n64, err = io.Copy(w, section.reader)
+			written += n64
+			if err != nil {
+				return written, fmt.Errorf("writing mapped file: %w", err)
+			}
+		}
+		return written, nil
+	})
+}
+
+// spoolMappedSections writes the entries and data sections of all elements to the spool files
+// and returns the number of written entries.
+func (m *Map[Key, Value]) spoolMappedSections(entries, data *spoolFile) (uint64, error) {
+	keyCodec, _ := newPrimitiveCodec[Key]()
+	valueCodec := m.getValueCodec()
+
+	var (
+		entry  [mappedEntrySize]byte
+		record []byte
+		value  []byte
+		err    error
+		offset uint64
+		count  uint64
+	)
+	for item := m.linkedList.First(); item != nil; item = item.Next() {
+		if m.ttl != 0 && m.isExpired(item, nanotime()) {
+			continue
+		}
+
+		value, err = valueCodec.AppendValue(value[:0], item.Value())
+		if err != nil {
+			return 0, fmt.Errorf("encoding value of key %v: %w", item.key, err)
+		}
+		record = keyCodec.append(record[:0], item.key)
+		record = binary.AppendUvarint(record, uint64(len(value)))
+		record = append(record, value...)
+
+		binary.LittleEndian.PutUint64(entry[0:8], uint64(item.keyHash))
+		binary.LittleEndian.PutUint64(entry[8:16], offset)
+		if _, err := entries.writer.Write(entry[:]); err != nil {
+			return 0, fmt.Errorf("writing entries: %w", err)
+		}
+		if _, err := data.writer.Write(record); err != nil {
+			return 0, fmt.Errorf("writing data: %w", err)
+		}
+		offset += uint64(len(record))
+		count++
+	}
+	return count, nil
+}
+
+// writeMappedDirectory writes the directory that contains the index of the first entry for
+// every bucket, the entries are read sequentially from the reader.
+func writeMappedDirectory(w io.Writer, entries io.Reader, count uint64, hashBits, bucketBits uint8) (int64, error) {
+	var entry [mappedEntrySize]byte
+	var buf [8]byte
+	var written int64
+
+	next := uint64(0)
+	hash := uint64(0)
+	readEntry := func() error {
+		if _, err := io.ReadFull(entries, entry[:]); err != nil {
+			return fmt.Errorf("reading entries: %w", err)
+		}
+		hash = binary.LittleEndian.Uint64(entry[:])
+		return nil
+	}
+	if count > 0 {
+		if err := readEntry(); err != nil {
+			return written, err
+		}
+	}
+
+	writeIndex := func(index uint64) error {
+		binary.LittleEndian.PutUint64(buf[:], index)
+		n, err := w.Write(buf[:])
+		written += int64(n)
+		if err != nil {
+			return fmt.Errorf("writing mapped file: %w", err)
+		}
+		return nil
+	}
+
+	for bucket := range uint64(1) << bucketBits {
+		for next < count && mappedBucket(hash, 64-hashBits, bucketBits) < bucket {
+			next++
+			if next < count {
+				if err := readEntry(); err != nil {
+					return written, err
+				}
+			}
+		}
+		if err := writeIndex(next); err != nil {
+			return written, err
+		}
+	}
+	return written, writeIndex(count)
+}
+
+// spoolFile is a temporary file that a section of a file is written to before it is copied
+// into the final file.
+type spoolFile struct {
+	file   *os.File
+	writer *bufio.Writer
+	reader *bufio.Reader
+}
+
+func newSpoolFile(dir string) (*spoolFile, error) {
+	file, err := os.CreateTemp(dir, ".spool-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating spool file: %w", err)
+	}
+	return &spoolFile{
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}, nil
+}
+
+// rewind flushes the written data and positions the reader at the start of the file.
+func (s *spoolFile) rewind() error {
+	if err := s.writer.Flush(); err != nil {
+		return fmt.Errorf("writing spool file: %w", err)
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking spool file: %w", err)
+	}
+	s.reader = bufio.NewReader(s.file)
+	return nil
+}
+
+// remove closes and removes the file.
+func (s *spoolFile) remove() {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
+
+// mappedBucket returns the bucket of a hash.
+func mappedBucket(hash uint64, hashShift, bucketBits uint8) uint64 {
+	return (hash << hashShift) >> (64 - bucketBits) // shifting by 64 results in 0
+}
+
+// OpenMapped opens a file that was written by Map.WriteMappedFile.
+// The file has to be closed by calling Close once the map is not used anymore.
+// Files written by a map with a custom hasher return ErrHasherRequired.
+func OpenMapped[Key hashable, Value any](path string) (*MappedMap[Key, Value], error) {
+	return openMapped[Key, Value](path, nil)
+}
+
+// OpenMappedWithHasher opens a file that was written by Map.WriteMappedFile using the given
+// hasher, it has to match the hasher of the map that the file was written from.
+func OpenMappedWithHasher[Key hashable, Value any](path string, hasher func(Key) uintptr) (*MappedMap[Key, Value], error) {
+	return openMapped[Key, Value](path, hasher)
+}
+
+func openMapped[Key hashable, Value any](path string, hasher func(Key) uintptr) (*MappedMap[Key, Value], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening mapped file: %w", err)
+	}
+	defer func() {
+		_ = file.Close() // the mapping stays valid after closing the file
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("reading file info: %w", err)
+	}
+	if info.Size() < mappedHeaderSize {
+		return nil, fmt.Errorf("%w: file too small", errInvalidSnapshot)
+	}
+
+	data, err := mmapFile(file, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	mm, err := newMappedMap[Key, Value](data, hasher)
+	if err != nil {
+		_ = munmapFile(data)
+		return nil, err
+	}
+	return mm, nil
+}
+
+func newMappedMap[Key hashable, Value any](data []byte, hasher func(Key) uintptr) (*MappedMap[Key, Value], error) {
+	keyCodec, _ := newPrimitiveCodec[Key]()
+	mm := &MappedMap[Key, Value]{
+		hasher:     hasher,
+		keyCodec:   keyCodec,
+		valueCodec: defaultValueCodec[Value](),
+		mapping:    data,
+	}
+
+	header := data[:mappedHeaderSize]
+	if string(header[:4]) != mappedMagic {
+		return nil, fmt.Errorf("%w: unknown file type", errInvalidSnapshot)
+	}
+	if crc32.ChecksumIEEE(header[:20]) != binary.LittleEndian.Uint32(header[20:24]) {
+		return nil, fmt.Errorf("%w: header", ErrChecksumMismatch)
+	}
+	if version := binary.LittleEndian.Uint16(header[4:6]); version != mappedVersion {
+		return nil, fmt.Errorf("%w %d", errUnsupportedFormat, version)
+	}
+	if kind := reflect.Kind(header[6]); kind != keyCodec.kind {
+		return nil, fmt.Errorf("file key kind %s does not match map key kind %s", kind, keyCodec.kind)
+	}
+	customHash := header[7] == hasherCustom
+	if customHash && hasher == nil {
+		return nil, ErrHasherRequired
+	}
+	if mm.hasher == nil {
+		mm.hasher = defaultHasher[Key]()
+	}
+	hashBits := header[8]
+	if !customHash && hashBits != strconv.IntSize {
+		return nil, fmt.Errorf("file hash size %d does not match platform hash size %d", hashBits, strconv.IntSize)
+	}
+	mm.hashShift = 64 - hashBits
+	mm.bucketBits = header[9]
+	mm.count = binary.LittleEndian.Uint64(header[12:20])
+
+	directorySize := ((uint64(1) << mm.bucketBits) + 1) * 8
+	entriesEnd := mappedHeaderSize + directorySize + mm.count*mappedEntrySize
+	if mm.bucketBits > 48 || mm.count > uint64(len(data)) || entriesEnd > uint64(len(data)) {
+		return nil, fmt.Errorf("%w: file too small", errInvalidSnapshot)
+	}
+	mm.directory = data[mappedHeaderSize : mappedHeaderSize+directorySize]
+	mm.entries = data[mappedHeaderSize+directorySize : entriesEnd]
+	mm.data = data[entriesEnd:]
+	return mm, nil
+}
+
+// SetValueCodec sets the codec that is used to decode values, it has to match the value codec
+// of the map that the file was written from.
+func (mm *MappedMap[Key, Value]) SetValueCodec(codec ValueCodec[Value]) {
+	mm.valueCodec = codec
+}
+
+// Len returns the number of elements within the map.
+func (mm *MappedMap[Key, Value]) Len() int {
+	return int(mm.count)
+}
+
+// Get retrieves an element from the map under given key.
+func (mm *MappedMap[Key, Value]) Get(key Key) (Value, bool) {
+	hash := uint64(mm.hasher(key))
+	bucket := mappedBucket(hash, mm.hashShift, mm.bucketBits)
+	first := binary.LittleEndian.Uint64(mm.directory[bucket*8:])
+	last := binary.LittleEndian.Uint64(mm.directory[bucket*8+8:])
+
+	for i := first; i < last && i < mm.count; i++ {
+		entry := mm.entries[i*mappedEntrySize:]
+		entryHash := binary.LittleEndian.Uint64(entry)
+		if entryHash > hash {
+			break
+		}
+		if entryHash != hash {
+			continue
+		}
+
+		entryKey, value, ok := mm.record(binary.LittleEndian.Uint64(entry[8:]))
+		if ok && entryKey == key {
+			return value, true
+		}
+	}
+	return *new(Value), false
+}
+
+// Range calls f sequentially for each key and value present in the map in the order of their hashed keys.
+// If f returns false, range stops the iteration.
+func (mm *MappedMap[Key, Value]) Range(f func(Key, Value) bool) {
+	for i := range mm.count {
+		offset := binary.LittleEndian.Uint64(mm.entries[i*mappedEntrySize+8:])
+		key, value, ok := mm.record(offset)
+		if !ok {
+			continue // skip corrupted records
+		}
+		if !f(key, value) {
+			return
+		}
+	}
+}
+
+// Close unmaps the file, the map must not be used afterwards.
+func (mm *MappedMap[Key, Value]) Close() error {
+	mapping := mm.mapping
+	mm.mapping, mm.data, mm.entries, mm.directory, mm.count = nil, nil, nil, nil, 0
+	return munmapFile(mapping)
+}
+
+// record decodes the record at the offset of the data section.
+func (mm *MappedMap[Key, Value]) record(offset uint64) (Key, Value, bool) {
+	var value Value
+	if offset >= uint64(len(mm.data)) {
+		return *new(Key), value, false
+	}
+	data := mm.data[offset:]
+
+	key, n, err := mm.keyCodec.decode(data)
+	if err != nil {
+		return key, value, false
+	}
+	data = data[n:]
+
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length {
+		return key, value, false
+	}
+	value, err = mm.valueCodec.DecodeValue(data[n : n+int(length)])
+	return key, value, err == nil
+}
@@ -0,0 +1,111 @@
+package hashmap
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestMappedMap(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.mapped")
+	m := New[string, int]()
+	itemCount := 1000
+	for i := range itemCount {
+		m.Set(strconv.Itoa(i), i)
+	}
+	assert.NoError(t, m.WriteMappedFile(path))
+	files, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files)) // the spool files are removed
+
+	mm, err := OpenMapped[string, int](path)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, mm.Close())
+	}()
+
+	assert.Equal(t, itemCount, mm.Len())
+	for i := range itemCount {
+		value, ok := mm.Get(strconv.Itoa(i))
+		assert.True(t, ok)
+		assert.Equal(t, i, value)
+	}
+	_, ok := mm.Get("missing")
+	assert.False(t, ok)
+
+	var lastHash uintptr
+	count := 0
+	mm.Range(func(key string, value int) bool {
+		hash := m.hasher(key)
+		assert.True(t, hash >= lastHash) // same order as the map
+		lastHash = hash
+		assert.Equal(t, strconv.Itoa(value), key)
+		count++
+		return true
+	})
+	assert.Equal(t, itemCount, count)
+}
+
+func TestMappedMapEmpty(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.mapped")
+	assert.NoError(t, New[int, string]().WriteMappedFile(path))
+
+	mm, err := OpenMapped[int, string](path)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, mm.Len())
+	_, ok := mm.Get(1)
+	assert.False(t, ok)
+	assert.NoError(t, mm.Close())
+}
+
+func TestMappedMapCustomHasher(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.mapped")
+	hasher := func(key int) uintptr {
+		return uintptr(key % 4) // force collisions
+	}
+	m := New[int, int]()
+	m.SetHasher(hasher)
+	for i := range 20 {
+		m.Set(i, i*2)
+	}
+	assert.NoError(t, m.WriteMappedFile(path))
+
+	_, err := OpenMapped[int, int](path)
+	assert.ErrorIs(t, err, ErrHasherRequired)
+
+	mm, err := OpenMappedWithHasher[int, int](path, hasher)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, mm.Close())
+	}()
+
+	for i := range 20 {
+		value, ok := mm.Get(i)
+		assert.True(t, ok)
+		assert.Equal(t, i*2, value)
+	}
+}
+
+func TestMappedMapInvalid(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.mapped")
+	m := New[int, int]()
+	m.Set(1, 1)
+	assert.NoError(t, m.WriteMappedFile(path))
+
+	_, err := OpenMapped[uint8, int](path)
+	assert.True(t, err != nil) // key kind mismatch
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	data[12]++ // element count
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+	_, err = OpenMapped[int, int](path)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
@@ -0,0 +1,124 @@
+package hashmap
+
+import (
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+const (
+	// maxSmallAllocation is the largest allocation that the Go runtime serves from size classes.
+	maxSmallAllocation = 32768
+	// tinyAllocation is the size below which pointer free allocations get combined into shared blocks.
+	tinyAllocation = 16
+	// allocationPageSize is the granularity of large allocations.
+	allocationPageSize = 8192
+)
+
+// allocationSizeClasses are the object sizes of the Go runtime memory allocator.
+var allocationSizeClasses = []int64{
+	8, 16, 24, 32, 48, 64, 80, 96, 112, 128, 144, 160, 176, 192, 208, 224, 240, 256,
+	288, 320, 352, 384, 416, 448, 480, 512, 576, 640, 704, 768, 896, 1024, 1152, 1280,
+	1408, 1536, 1792, 2048, 2304, 2688, 3072, 3200, 3456, 4096, 4864, 5376, 6144, 6528,
+	6784, 6912, 8192, 9472, 9728, 10240, 10880, 12288, 13568, 14336, 16384, 18432, 19072,
+	20480, 21760, 24576, 27264, 28672, 32768,
+}
+
+// MemoryEstimate is an estimation of the heap memory in bytes that a map uses.
+type MemoryEstimate struct {
+	Index    int64 // index slots, including a second index that is referenced while resizing
+	Elements int64 // list elements and the boxes that hold the values
+	Keys     int64 // data of keys that is stored outside of the list elements, like string contents
+	Values   int64 // memory referenced by values as reported by the value sizer
+	Overhead int64 // fixed size structures of the map
+}
+
+// Total returns the sum of all estimated bytes.
+func (e MemoryEstimate) Total() int64 {
+	return e.Index + e.Elements + e.Keys + e.Values + e.Overhead
+}
+
+// MemoryUsage estimates the heap memory that the map uses, based on the allocation size classes
+// of the Go runtime. The optional value sizer returns the memory that a value references outside
+// of its own size, like the contents of a slice. String keys and the value sizer require to walk
+// all elements of the map.
+func (m *Map[Key, Value]) MemoryUsage(valueSizer func(Value) int64) MemoryEstimate {
+	var estimate MemoryEstimate
+	for _, store := range []*store[Key, Value]{m.store.Load(), m.resizeStore.Load()} {
+		if store != nil {
+			estimate.Index += allocationSize(int64(len(store.index))*intSizeBytes, true)
+			estimate.Overhead += allocationSize(int64(unsafe.Sizeof(*store)), true)
+		}
+	}
+
+	count := int64(m.Len())
+	elementSize := allocationSize(int64(unsafe.Sizeof(ListElement[Key, Value]{})), true)
+	valueType := reflect.TypeFor[Value]()
+	valueSize := allocationSize(int64(valueType.Size()), typeHasPointers(valueType))
+	if m.linkedList.boxed {
+		valueSize = allocationSize(int64(unsafe.Sizeof(valueBox[Value]{})), true) +
+			allocationSize(int64(unsafe.Sizeof(elementMeta{})), false)
+	}
+	estimate.Elements = count * (elementSize + valueSize)
+
+	estimate.Overhead += allocationSize(int64(unsafe.Sizeof(*m)), true) +
+		allocationSize(int64(unsafe.Sizeof(*m.linkedList)), true) +
+		elementSize // list head
+	if m.counters != nil {
+		estimate.Overhead += allocationSize(int64(unsafe.Sizeof(*m.counters)), true)
+	}
+
+	stringKeys := reflect.TypeFor[Key]().Kind() == reflect.String
+	if !stringKeys && valueSizer == nil {
+		return estimate
+	}
+
+	for item := m.linkedList.First(); item != nil; item = item.Next() {
+		if stringKeys {
+			length := int64(len(*(*string)(unsafe.Pointer(&item.key))))
+			estimate.Keys += allocationSize(length, false)
+		}
+		if valueSizer != nil {
+			estimate.Values += valueSizer(item.Value())
+		}
+	}
+	return estimate
+}
+
+// allocationSize returns the number of bytes that the Go runtime uses for an allocation of the
+// given size. Small pointer free allocations are combined and estimated by their size.
+func allocationSize(size int64, pointers bool) int64 {
+	switch {
+	case size == 0:
+		return 0
+	case !pointers && size < tinyAllocation:
+		return size
+	case size > maxSmallAllocation:
+		return (size + allocationPageSize - 1) / allocationPageSize * allocationPageSize
+	}
+
+	i := sort.Search(len(allocationSizeClasses), func(i int) bool {
+		return allocationSizeClasses[i] >= size
+	})
+	return allocationSizeClasses[i]
+}
+
+// typeHasPointers returns whether values of the type contain pointers.
+func typeHasPointers(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.String, reflect.Slice, reflect.Map, reflect.Chan,
+		reflect.Func, reflect.Interface, reflect.UnsafePointer:
+		return true
+	case reflect.Array:
+		return typ.Len() > 0 && typeHasPointers(typ.Elem())
+	case reflect.Struct:
+		for i := range typ.NumField() {
+			if typeHasPointers(typ.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
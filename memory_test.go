@@ -0,0 +1,110 @@
+package hashmap
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestAllocationSize(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, 0, allocationSize(0, true))
+	assert.Equal(t, 5, allocationSize(5, false))
+	assert.Equal(t, 8, allocationSize(5, true))
+	assert.Equal(t, 48, allocationSize(33, true))
+	assert.Equal(t, 32768, allocationSize(32768, false))
+	assert.Equal(t, 40960, allocationSize(32769, false))
+}
+
+func TestMemoryUsage(t *testing.T) {
+	t.Parallel()
+	m := New[string, []byte]()
+	empty := m.MemoryUsage(nil)
+	assert.Equal(t, 0, empty.Elements)
+	assert.Equal(t, defaultSize*intSizeBytes, empty.Index)
+	assert.True(t, empty.Overhead > 0)
+
+	for i := range 100 {
+		m.Set(fmt.Sprintf("key-%016d", i), make([]byte, 100))
+	}
+	waitFor(t, func() bool {
+		return m.resizing.Load() == 0
+	})
+
+	estimate := m.MemoryUsage(func(value []byte) int64 {
+		return allocationSize(int64(cap(value)), false)
+	})
+	elementSize := allocationSize(int64(unsafe.Sizeof(ListElement[string, []byte]{})), true)
+	assert.Equal(t, 100*(elementSize+allocationSize(int64(unsafe.Sizeof([]byte{})), true)), estimate.Elements)
+	assert.Equal(t, 100*24, estimate.Keys) // 20 bytes are allocated in the 24 bytes size class
+	assert.Equal(t, 100*112, estimate.Values)
+	assert.Equal(t, int64(len(m.store.Load().index))*intSizeBytes, estimate.Index)
+	assert.Equal(t, estimate.Index+estimate.Elements+estimate.Keys+estimate.Values+estimate.Overhead, estimate.Total())
+}
+
+func TestMemoryUsageElementMeta(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.SetMemoryBudget(func(int, int) int64 { return 1 }, 1000)
+	for i := range 10 {
+		m.Set(i, i)
+	}
+
+	elementSize := allocationSize(int64(unsafe.Sizeof(ListElement[int, int]{})), true)
+	boxSize := allocationSize(int64(unsafe.Sizeof(valueBox[int]{})), true) +
+		allocationSize(int64(unsafe.Sizeof(elementMeta{})), false)
+	assert.Equal(t, 10*(elementSize+boxSize), m.MemoryUsage(nil).Elements)
+}
+
+func BenchmarkMemoryUsage(b *testing.B) {
+	b.Run("int", func(b *testing.B) {
+		benchmarkMemoryUsage(b, func(i int) int { return i }, func(i int) int { return i }, nil)
+	})
+	b.Run("string", func(b *testing.B) {
+		benchmarkMemoryUsage(b, func(i int) string { return fmt.Sprintf("key-%016d", i) },
+			func(i int) uint64 { return uint64(i) }, nil)
+	})
+	b.Run("uint32-string", func(b *testing.B) {
+		benchmarkMemoryUsage(b, func(i int) uint32 { return uint32(i) },
+			func(i int) string { return fmt.Sprintf("value-%026d", i) },
+			func(value string) int64 { return allocationSize(int64(len(value)), false) })
+	})
+}
+
+// benchmarkMemoryUsage compares the estimated memory usage with the heap growth that
+// the runtime reports for creating a map.
+func benchmarkMemoryUsage[Key hashable, Value any](b *testing.B, key func(int) Key, value func(int) Value,
+	valueSizer func(Value) int64) {
+	b.Helper()
+	const count = 10_000
+
+	for range b.N {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		m := New[Key, Value]()
+		for i := range count {
+			m.Set(key(i), value(i))
+		}
+		for m.resizing.Load() != 0 {
+			runtime.Gosched()
+		}
+
+		runtime.GC()
+		runtime.GC() // release objects that sync pools retained for one cycle
+		runtime.ReadMemStats(&after)
+		estimate := m.MemoryUsage(valueSizer).Total()
+		runtime.KeepAlive(m)
+
+		actual := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+		ratio := float64(estimate) / float64(actual)
+		b.ReportMetric(ratio, "estimate/actual")
+		if ratio < 0.85 || ratio > 1.15 {
+			b.Fatalf("estimated %d bytes but heap grew by %d bytes", estimate, actual)
+		}
+	}
+}
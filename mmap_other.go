@@ -0,0 +1,23 @@
+//go:build !unix
+
+package hashmap
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// mmapFile reads the file into memory on platforms without mmap support.
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	return data, nil
+}
+
+// munmapFile releases a file that was read by mmapFile.
+func munmapFile(_ []byte) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package hashmap
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the file read-only into memory.
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("mapping file: %w", err)
+	}
+	return data, nil
+}
+
+// munmapFile unmaps a file that was mapped by mmapFile.
+func munmapFile(data []byte) error {
+	if data == nil {
+		return nil
+	}
+	if err := syscall.Munmap(data); err != nil {
+		return fmt.Errorf("unmapping file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package hashmap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultFlushInterval is the default interval in which pending writes of a write-behind map get persisted.
+const defaultFlushInterval = time.Second
+
+// storeMapLockStripes is the number of mutexes that serialize the changes of a StoreMap
+// per key, it has to be a power of 2.
+const storeMapLockStripes = 64
+
+// Store is a backing store that persists the entries of a map.
+type Store[Key hashable, Value any] interface {
+	// Load returns the value for the key, the bool reports whether the key exists in the store.
+	Load(key Key) (Value, bool, error)
+	// Save persists the value under the key.
+	Save(key Key, value Value) error
+	// Delete removes the key from the store. Deleting a non existing key is not an error.
+	Delete(key Key) error
+}
+
+// WriteBehindOptions configures the write-behind mode of a StoreMap.
+type WriteBehindOptions struct {
+	// FlushInterval is the interval in which pending writes get persisted, defaults to 1 second.
+	FlushInterval time.Duration
+
+	// MaxPending is the number of pending writes that triggers a flush before the interval passed.
+	// A value of 0 disables the limit.
+	MaxPending int
+
+	// OnError gets called with the error of a failed background flush.
+	// Failed writes stay pending and get retried on the next flush.
+	OnError func(err error)
+}
+
+// StoreMap wraps a map and persists all changes to a backing Store, either synchronously
+// (write-through) or batched in the background (write-behind).
+// Keys that are missing in the map get loaded from the store on access.
+type StoreMap[Key hashable, Value any] struct {
+	m     *Map[Key, Value]
+	store Store[Key, Value]
+
+	// keyLocks serialize the changes of a key, so that the map and the store
+	// apply concurrent changes of the same key in the same order
+	keyLocks [storeMapLockStripes]sync.Mutex
+
+	writeBehind bool
+	options     WriteBehindOptions
+	mu          sync.Mutex                  // protects pending and the removal of writes from flushing
+	pending     map[Key]pendingWrite[Value] // latest not yet persisted write for every key
+	flushing    map[Key]pendingWrite[Value] // writes that the running flush is persisting
+	flushMu     sync.Mutex                  // serializes flushes
+	trigger     chan struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+	flusher     sync.WaitGroup
+}
+
+// pendingWrite is a not yet persisted change of a write-behind map.
+type pendingWrite[Value any] struct {
+	value   Value
+	deleted bool
+}
+
+// NewWriteThrough returns a StoreMap that persists every change synchronously before applying it to the map.
+func NewWriteThrough[Key hashable, Value any](m *Map[Key, Value], store Store[Key, Value]) *StoreMap[Key, Value] {
+	return &StoreMap[Key, Value]{
+		m:     m,
+		store: store,
+	}
+}
+
+// NewWriteBehind returns a StoreMap that applies changes to the map immediately and persists them
+// in the background. Repeated writes to the same key between two flushes are coalesced.
+// Pending writes are persisted by calling Flush or Close.
+func NewWriteBehind[Key hashable, Value any](m *Map[Key, Value], store Store[Key, Value], options WriteBehindOptions) *StoreMap[Key, Value] {
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = defaultFlushInterval
+	}
+
+	s := &StoreMap[Key, Value]{
+		m:           m,
+		store:       store,
+		writeBehind: true,
+		options:     options,
+		pending:     map[Key]pendingWrite[Value]{},
+		trigger:     make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+
+	s.flusher.Add(1)
+	go s.flushLoop()
+	return s
+}
+
+// Map returns the wrapped map.
+func (s *StoreMap[Key, Value]) Map() *Map[Key, Value] {
+	return s.m
+}
+
+// Get returns the value for the key. If the key is not in the map it gets loaded from the store
+// and added to the map. The returned bool reports whether the key exists.
+func (s *StoreMap[Key, Value]) Get(key Key) (Value, bool, error) {
+	if value, ok := s.m.Get(key); ok {
+		return value, true, nil
+	}
+
+	lock := s.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if value, ok := s.m.Get(key); ok { // loaded or set concurrently
+		return value, true, nil
+	}
+
+	if s.writeBehind {
+		if write, ok := s.pendingWrite(key); ok && write.deleted { // the store still contains the deleted value
+			return *new(Value), false, nil
+		}
+	}
+
+	value, ok, err := s.store.Load(key)
+	if err != nil || !ok {
+		return *new(Value), false, err
+	}
+	actual, _ := s.m.GetOrInsert(key, value)
+	return actual, true, nil
+}
+
+// Set sets the value under the key in the map and the store.
+// In write-through mode the map is only updated if the store saved the value successfully.
+func (s *StoreMap[Key, Value]) Set(key Key, value Value) error {
+	lock := s.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !s.writeBehind {
+		if err := s.store.Save(key, value); err != nil {
+			return err
+		}
+		s.m.Set(key, value)
+		return nil
+	}
+
+	s.m.Set(key, value)
+	s.addPending(key, pendingWrite[Value]{value: value})
+	return nil
+}
+
+// Del deletes the key from the map and the store.
+// In write-through mode the map is only updated if the store deleted the key successfully.
+func (s *StoreMap[Key, Value]) Del(key Key) error {
+	lock := s.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !s.writeBehind {
+		if err := s.store.Delete(key); err != nil {
+			return err
+		}
+		s.m.Del(key)
+		return nil
+	}
+
+	s.m.Del(key)
+	s.addPending(key, pendingWrite[Value]{deleted: true})
+	return nil
+}
+
+// Pending returns the number of writes that have not been persisted yet.
+func (s *StoreMap[Key, Value]) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// Flush persists all pending writes. Writes that failed stay pending and the
+// errors are returned. It is a no-op in write-through mode.
+func (s *StoreMap[Key, Value]) Flush(ctx context.Context) error {
+	if !s.writeBehind {
+		return nil
+	}
+
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[Key]pendingWrite[Value], len(batch))
+	s.flushing = batch // keeps the writes visible to Get until the store applied them
+	s.mu.Unlock()
+
+	var errs []error
+	for key, write := range batch {
+		if err := ctx.Err(); err != nil {
+			s.requeue(batch)
+			return errors.Join(append(errs, err)...)
+		}
+
+		var err error
+		if write.deleted {
+			err = s.store.Delete(key)
+		} else {
+			err = s.store.Save(key, write.value)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.mu.Lock()
+		delete(batch, key)
+		s.mu.Unlock()
+	}
+
+	s.requeue(batch)
+	return errors.Join(errs...)
+}
+
+// Close stops the background flushing and persists all pending writes.
+func (s *StoreMap[Key, Value]) Close(ctx context.Context) error {
+	if !s.writeBehind {
+		return nil
+	}
+
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	s.flusher.Wait()
+	return s.Flush(ctx)
+}
+
+// keyLock returns the mutex that serializes the changes of the key.
+func (s *StoreMap[Key, Value]) keyLock(key Key) *sync.Mutex {
+	return &s.keyLocks[s.m.hasher(key)&(storeMapLockStripes-1)]
+}
+
+func (s *StoreMap[Key, Value]) addPending(key Key, write pendingWrite[Value]) {
+	s.mu.Lock()
+	s.pending[key] = write
+	count := len(s.pending)
+	s.mu.Unlock()
+
+	if s.options.MaxPending > 0 && count >= s.options.MaxPending {
+		select {
+		case s.trigger <- struct{}{}:
+		default: // a flush is already triggered
+		}
+	}
+}
+
+// pendingWrite returns the latest write of the key that is not persisted yet.
+func (s *StoreMap[Key, Value]) pendingWrite(key Key) (pendingWrite[Value], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if write, ok := s.pending[key]; ok {
+		return write, true
+	}
+	write, ok := s.flushing[key]
+	return write, ok
+}
+
+// requeue adds the writes that could not be persisted back to the pending writes,
+// unless the key was written again in the meantime, and ends the flush.
+func (s *StoreMap[Key, Value]) requeue(batch map[Key]pendingWrite[Value]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushing = nil
+	for key, write := range batch {
+		if _, ok := s.pending[key]; !ok {
+			s.pending[key] = write
+		}
+	}
+}
+
+func (s *StoreMap[Key, Value]) flushLoop() {
+	defer s.flusher.Done()
+
+	ticker := time.NewTicker(s.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+
+		if err := s.Flush(context.Background()); err != nil && s.options.OnError != nil {
+			s.options.OnError(err)
+		}
+	}
+}
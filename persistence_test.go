@@ -0,0 +1,205 @@
+package hashmap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+// memoryStore is a Store for testing that counts the number of writes.
+type memoryStore struct {
+	mu      sync.Mutex
+	data    map[string]int
+	saves   int
+	deletes int
+	err     error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{data: map[string]int{}}
+}
+
+func (s *memoryStore) Load(key string) (int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.data[key]
+	return value, ok, nil
+}
+
+func (s *memoryStore) Save(key string, value int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.saves++
+	s.data[key] = value
+	return nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.deletes++
+	delete(s.data, key)
+	return nil
+}
+
+// blockingStore is a memoryStore whose deletes wait until they are released.
+type blockingStore struct {
+	*memoryStore
+	deleting chan string
+	release  chan struct{}
+}
+
+func (s *blockingStore) Delete(key string) error {
+	s.deleting <- key
+	<-s.release
+	return s.memoryStore.Delete(key)
+}
+
+func TestWriteThrough(t *testing.T) {
+	t.Parallel()
+	store := newMemoryStore()
+	store.data["stored"] = 1
+	s := NewWriteThrough(New[string, int](), store)
+
+	assert.NoError(t, s.Set("a", 2))
+	assert.Equal(t, 2, store.data["a"])
+
+	value, ok, err := s.Get("stored") // read-through
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 2, s.Map().Len())
+
+	assert.NoError(t, s.Del("a"))
+	_, ok = store.data["a"]
+	assert.False(t, ok)
+
+	errSave := errors.New("save failed")
+	store.err = errSave
+	assert.ErrorIs(t, s.Set("b", 3), errSave)
+	_, ok = s.Map().Get("b")
+	assert.False(t, ok)
+}
+
+func TestWriteBehind(t *testing.T) {
+	t.Parallel()
+	store := newMemoryStore()
+	store.data["deleted"] = 1
+	s := NewWriteBehind(New[string, int](), store, WriteBehindOptions{
+		FlushInterval: time.Hour,
+	})
+
+	for i := range 10 {
+		assert.NoError(t, s.Set("a", i))
+	}
+	assert.NoError(t, s.Del("deleted"))
+	assert.Equal(t, 2, s.Pending())
+
+	_, ok, err := s.Get("deleted") // pending delete hides the stored value
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, s.Flush(context.Background()))
+	assert.Equal(t, 0, s.Pending())
+	assert.Equal(t, 1, store.saves) // repeated writes got coalesced
+	assert.Equal(t, 1, store.deletes)
+	assert.Equal(t, 9, store.data["a"])
+
+	errSave := errors.New("save failed")
+	store.err = errSave
+	assert.NoError(t, s.Set("b", 1))
+	assert.ErrorIs(t, s.Flush(context.Background()), errSave)
+	assert.Equal(t, 1, s.Pending())
+
+	store.err = nil
+	assert.NoError(t, s.Close(context.Background()))
+	assert.Equal(t, 1, store.data["b"])
+}
+
+func TestWriteBehindFlushingDelete(t *testing.T) {
+	t.Parallel()
+	store := &blockingStore{
+		memoryStore: newMemoryStore(),
+		deleting:    make(chan string),
+		release:     make(chan struct{}),
+	}
+	s := NewWriteBehind(New[string, int](), store, WriteBehindOptions{
+		FlushInterval: time.Hour,
+	})
+
+	assert.NoError(t, s.Set("a", 1))
+	assert.NoError(t, s.Flush(context.Background()))
+	assert.NoError(t, s.Del("a"))
+
+	flushed := make(chan error)
+	go func() {
+		flushed <- s.Flush(context.Background())
+	}()
+	assert.Equal(t, "a", <-store.deleting)
+
+	_, ok, err := s.Get("a") // the delete that is being flushed hides the stored value
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	close(store.release)
+	assert.NoError(t, <-flushed)
+	_, ok = s.Map().Get("a")
+	assert.False(t, ok)
+	_, ok, err = s.Get("a")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, s.Close(context.Background()))
+}
+
+func TestWriteBehindMaxPending(t *testing.T) {
+	t.Parallel()
+	store := newMemoryStore()
+	s := NewWriteBehind(New[string, int](), store, WriteBehindOptions{
+		FlushInterval: time.Hour,
+		MaxPending:    2,
+	})
+	defer func() {
+		assert.NoError(t, s.Close(context.Background()))
+	}()
+
+	assert.NoError(t, s.Set("a", 1))
+	assert.NoError(t, s.Set("b", 2))
+	waitFor(t, func() bool {
+		return s.Pending() == 0
+	})
+}
+
+func TestWriteThroughConcurrentSet(t *testing.T) {
+	t.Parallel()
+	store := newMemoryStore()
+	s := NewWriteThrough(New[string, int](), store)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				assert.NoError(t, s.Set("key", i*100+j))
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, ok := s.Map().Get("key")
+	assert.True(t, ok)
+	stored, ok, err := store.Load("key")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, stored, value)
+}
@@ -0,0 +1,184 @@
+package hashmap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var errInvalidLabel = errors.New("invalid label name")
+
+// labelValueEscaper escapes label values as required by the Prometheus text format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// StatsProvider is implemented by all maps and provides the statistics that a Collector exports.
+type StatsProvider interface {
+	Stats() Stats
+}
+
+// Collector renders the statistics of registered maps in the Prometheus text exposition format.
+// It implements http.Handler to be used as scrape endpoint.
+type Collector struct {
+	mu      sync.RWMutex
+	sources []collectorSource
+}
+
+// collectorSource is a registered map with its rendered labels.
+type collectorSource struct {
+	provider StatsProvider
+	labels   string // rendered label pairs without braces
+}
+
+// collectorMetric describes a metric family and how to get its value from the statistics.
+type collectorMetric struct {
+	name     string
+	help     string
+	typ      string
+	label    string // additional label pair of the metric, optional
+	counting bool   // only exported for maps with enabled operation counters
+	value    func(Stats) float64
+}
+
+var collectorMetrics = []collectorMetric{
+	{name: "hashmap_entries", help: "Number of elements within the map.", typ: "gauge",
+		value: func(s Stats) float64 { return float64(s.Len) }},
+	{name: "hashmap_index_slots", help: "Number of slots of the index.", typ: "gauge",
+		value: func(s Stats) float64 { return float64(s.IndexSize) }},
+	{name: "hashmap_fill_ratio", help: "Ratio of filled index slots.", typ: "gauge",
+		value: func(s Stats) float64 { return float64(s.FillRate) / 100 }},
+	{name: "hashmap_weight", help: "Total weight of all elements as reported by the sizer of the memory budget.", typ: "gauge",
+		value: func(s Stats) float64 { return float64(s.Weight) }},
+	{name: "hashmap_resizes_total", help: "Number of times that the index was replaced by a resized one.", typ: "counter",
+		value: func(s Stats) float64 { return float64(s.Resizes) }},
+	{name: "hashmap_evictions_total", help: "Number of elements evicted to stay within the memory budget.", typ: "counter",
+		value: func(s Stats) float64 { return float64(s.Evictions) }},
+	{name: "hashmap_operations_total", help: "Number of map operations by result.", typ: "counter",
+		label: `op="hit"`, counting: true, value: func(s Stats) float64 { return float64(s.Hits) }},
+	{name: "hashmap_operations_total", label: `op="miss"`, counting: true,
+		value: func(s Stats) float64 { return float64(s.Misses) }},
+	{name: "hashmap_operations_total", label: `op="insert"`, counting: true,
+		value: func(s Stats) float64 { return float64(s.Inserts) }},
+	{name: "hashmap_operations_total", label: `op="update"`, counting: true,
+		value: func(s Stats) float64 { return float64(s.Updates) }},
+	{name: "hashmap_operations_total", label: `op="delete"`, counting: true,
+		value: func(s Stats) float64 { return float64(s.Deletes) }},
+	{name: "hashmap_cas_retries_total", help: "Number of write operations that had to retry due to concurrent changes.", typ: "counter",
+		counting: true, value: func(s Stats) float64 { return float64(s.CASRetries) }},
+}
+
+// NewCollector returns a new collector without registered maps.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Register adds a map to the collector. The labels are added to all metrics of the map to
+// distinguish it from other registered maps.
+func (c *Collector) Register(provider StatsProvider, labels map[string]string) error {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if !validLabelName(name) {
+			return fmt.Errorf("%w: %q", errInvalidLabel, name)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+`="`+labelValueEscaper.Replace(labels[name])+`"`)
+	}
+
+	c.mu.Lock()
+	c.sources = append(c.sources, collectorSource{
+		provider: provider,
+		labels:   strings.Join(pairs, ","),
+	})
+	c.mu.Unlock()
+	return nil
+}
+
+// ServeHTTP writes the metrics of all registered maps in the Prometheus text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	_, _ = c.WriteTo(w)
+}
+
+// WriteTo writes the metrics of all registered maps in the Prometheus text format to w.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.RLock()
+	sources := slices.Clone(c.sources)
+	c.mu.RUnlock()
+
+	stats := make([]Stats, len(sources))
+	for i, source := range sources {
+		stats[i] = source.provider.Stats()
+	}
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, metric := range collectorMetrics {
+		if metric.help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.typ)
+		}
+
+		for i, source := range sources {
+			if metric.counting && !stats[i].Counting {
+				continue
+			}
+
+			labels := source.labels
+			if metric.label != "" {
+				if labels != "" {
+					labels += ","
+				}
+				labels += metric.label
+			}
+
+			bw.WriteString(metric.name)
+			if labels != "" {
+				bw.WriteString("{" + labels + "}")
+			}
+			bw.WriteByte(' ')
+			bw.WriteString(strconv.FormatFloat(metric.value(stats[i]), 'g', -1, 64))
+			bw.WriteByte('\n')
+		}
+	}
+
+	err := bw.Flush()
+	return cw.written, err
+}
+
+// validLabelName returns whether the name is a valid Prometheus label name.
+func validLabelName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "__") {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.written += int64(n)
+	return n, err
+}
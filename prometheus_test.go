@@ -0,0 +1,71 @@
+package hashmap
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+	users := New[int, string]()
+	users.EnableStats()
+	users.Set(1, "a")
+	users.Get(1)
+	users.Get(2)
+
+	sessions := New[string, int]()
+	sessions.Set("s", 1)
+
+	c := NewCollector()
+	assert.NoError(t, c.Register(users, map[string]string{"name": "users", "service": `a"b\c`}))
+	assert.NoError(t, c.Register(sessions, map[string]string{"name": "sessions"}))
+
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, prometheusContentType, resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	output := string(body)
+
+	for _, line := range []string{
+		"# TYPE hashmap_entries gauge",
+		`hashmap_entries{name="users",service="a\"b\\c"} 1`,
+		`hashmap_entries{name="sessions"} 1`,
+		`hashmap_index_slots{name="sessions"} 8`,
+		`hashmap_fill_ratio{name="sessions"} 0.12`,
+		"# TYPE hashmap_operations_total counter",
+		`hashmap_operations_total{name="users",service="a\"b\\c",op="hit"} 1`,
+		`hashmap_operations_total{name="users",service="a\"b\\c",op="miss"} 1`,
+		`hashmap_operations_total{name="users",service="a\"b\\c",op="insert"} 1`,
+	} {
+		assert.True(t, strings.Contains(output, line+"\n"), line)
+	}
+	// operation counters are only exported for maps with enabled stats
+	assert.False(t, strings.Contains(output, `hashmap_operations_total{name="sessions"`))
+	assert.Equal(t, 1, strings.Count(output, "# TYPE hashmap_operations_total"))
+}
+
+func TestCollectorInvalidLabel(t *testing.T) {
+	t.Parallel()
+	c := NewCollector()
+	m := New[int, int]()
+	assert.ErrorIs(t, c.Register(m, map[string]string{"1name": "x"}), errInvalidLabel)
+	assert.ErrorIs(t, c.Register(m, map[string]string{"__name": "x"}), errInvalidLabel)
+	assert.ErrorIs(t, c.Register(m, map[string]string{"na-me": "x"}), errInvalidLabel)
+	assert.NoError(t, c.Register(m, nil))
+
+	recorder := httptest.NewRecorder()
+	c.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.True(t, strings.Contains(recorder.Body.String(), "\nhashmap_entries 0\n"))
+}
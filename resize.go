@@ -0,0 +1,34 @@
+package hashmap
+
+import (
+	"time"
+)
+
+// ResizeEvent describes a finished resize of the index of a map.
+type ResizeEvent struct {
+	OldSize int // number of slots of the previous index
+	NewSize int // number of slots of the new index
+	Count   int // number of elements within the map after the resize
+
+	// FirstFill is the time spent filling the new index before it replaced the previous one.
+	FirstFill time.Duration
+	// SecondFill is the time spent updating the new index with elements that were
+	// inserted concurrently during the first fill.
+	SecondFill time.Duration
+
+	// FollowUp reports whether the fill rate is still too high after the resize and
+	// another doubling of the index follows immediately.
+	FollowUp bool
+}
+
+// Duration returns the total time of the resize.
+func (e ResizeEvent) Duration() time.Duration {
+	return e.FirstFill + e.SecondFill
+}
+
+// OnResize sets a function that gets called after every resize of the index of the map.
+// It is called from the goroutine that performs the resize and should return quickly.
+// It has to be called before the map is used.
+func (m *Map[Key, Value]) OnResize(f func(ResizeEvent)) {
+	m.onResize = f
+}
@@ -0,0 +1,75 @@
+package hashmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestOnResize(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+
+	var mu sync.Mutex
+	var events []ResizeEvent
+	m.OnResize(func(event ResizeEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	for i := range 100 {
+		m.Set(i, i)
+	}
+	waitFor(t, func() bool { // wait for the background resizes caused by the inserts
+		return m.resizing.Load() == 0
+	})
+	m.Grow(1024)
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) > 0 && events[len(events)-1].NewSize == 1024
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, defaultSize, events[0].OldSize)
+	for i, event := range events {
+		assert.True(t, event.NewSize > event.OldSize)
+		assert.True(t, event.Duration() >= 0)
+		if i > 0 {
+			assert.Equal(t, events[i-1].NewSize, event.OldSize)
+		}
+	}
+	last := events[len(events)-1]
+	assert.Equal(t, 100, last.Count)
+	assert.False(t, last.FollowUp)
+	assert.Equal(t, uint64(len(events)), m.Stats().Resizes)
+}
+
+func TestOnResizeFollowUp(t *testing.T) {
+	t.Parallel()
+	b := NewBuilder[int, int](100)
+	for i := range 100 {
+		b.Add(i, i)
+	}
+	m := b.Build()
+	m.store.Store(makeStore[int, int](defaultSize)) // simulate a too small index
+
+	events := make(chan ResizeEvent, 10)
+	m.OnResize(func(event ResizeEvent) {
+		events <- event
+	})
+	m.Grow(0)
+
+	event := <-events
+	assert.Equal(t, defaultSize, event.OldSize)
+	assert.Equal(t, 2*defaultSize, event.NewSize)
+	assert.True(t, event.FollowUp)
+	for event.FollowUp {
+		event = <-events
+	}
+	assert.Equal(t, int(indexSizeFor(100)), event.NewSize)
+}
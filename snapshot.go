@@ -0,0 +1,405 @@
+package hashmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"reflect"
+)
+
+// Snapshot format
+//
+// A snapshot starts with a header, followed by blocks of records and an empty end block.
+// All integers are stored in little endian byte order.
+//
+//	header:
+//	  magic       [4]byte  "HMAP"
+//	  version     uint16   snapshotVersion or deltaVersion
+//	  key kind    uint8    reflect.Kind of the key type
+//	  hasher id   uint8    hasherCustom or hasherDefault
+//	  count       uint64   number of elements when the snapshot was started
+//	  since       uint64   only version 2: map version after which changes are included
+//	  map version uint64   only version 2: map version when the snapshot was started
+//	  checksum    uint32   CRC32 (IEEE) of all previous header bytes
+//
+//	block:
+//	  records     uint32   number of records in the block, 0 marks the end block
+//	  length      uint32   byte length of the records
+//	  records     []record
+//	  checksum    uint32   CRC32 (IEEE) of the record count, length and records
+//
+//	record:
+//	  length      uvarint  byte length of the following record fields
+//	  hash        uint64   hashed key
+//	  version     uint64   only version 2: map version of the last change of the element
+//	  flags       uint8    only version 2: recordDeleted for deleted elements
+//	  key         fixed width numeric or uvarint length prefixed string
+//	  value       []byte   value encoded by the value codec of the map, empty for deleted elements
+//
+// Records of existing elements are stored in the order of their hashed keys. Version 2 is
+// used for delta snapshots, records of deleted elements follow after all existing elements.
+const (
+	snapshotMagic      = "HMAP"
+	snapshotVersion    = 1
+	deltaVersion       = 2
+	snapshotHeaderSize = 4 + 2 + 1 + 1 + 8 + 4 // size of a version 1 header
+	deltaHeaderSize    = snapshotHeaderSize + 16
+	recordDeleted      = 1
+	snapshotBlockSize  = 64 << 10 // targeted maximum byte length of the records of a block
+	snapshotMaxBlock   = 1 << 30  // maximum byte length of the records of a block that is accepted when reading
+
+	hasherCustom  = 0
+	hasherDefault = 1
+)
+
+// ErrChecksumMismatch is returned when reading a snapshot that contains corrupted data.
+var ErrChecksumMismatch = errors.New("snapshot checksum mismatch")
+
+var errInvalidSnapshot = errors.New("invalid snapshot")
+
+// snapshotHeader is the header of a snapshot.
+type snapshotHeader struct {
+	version    uint16
+	keyKind    reflect.Kind
+	hasherID   uint8
+	count      uint64
+	since      uint64 // only set for version 2
+	mapVersion uint64 // only set for version 2
+}
+
+// snapshotRecord is a raw record of a snapshot.
+type snapshotRecord struct {
+	hash    uint64
+	version uint64 // only set for version 2
+	deleted bool   // only set for version 2
+	key     []byte // encoded key
+	value   []byte // encoded value
+}
+
+// appendRecord appends the fields of a record for the given snapshot format version to the buffer.
+func appendRecord(buf []byte, formatVersion uint16, record snapshotRecord) []byte {
+	buf = binary.LittleEndian.AppendUint64(buf, record.hash)
+	if formatVersion == deltaVersion {
+		buf = binary.LittleEndian.AppendUint64(buf, record.version)
+		flags := byte(0)
+		if record.deleted {
+			flags |= recordDeleted
+		}
+		buf = append(buf, flags)
+	}
+	buf = append(buf, record.key...)
+	return append(buf, record.value...)
+}
+
+// WriteTo writes a snapshot of the map to w and returns the number of bytes written.
+// The snapshot is written while other goroutines can modify the map. It is not a consistent
+// point-in-time copy: every element contains the value at the time it was visited, elements
+// that are added or deleted concurrently may or may not be included.
+func (m *Map[Key, Value]) WriteTo(w io.Writer) (int64, error) {
+	keyCodec, _ := newPrimitiveCodec[Key]()
+	valueCodec := m.getValueCodec()
+
+	var (
+		count int
+		first *ListElement[Key, Value]
+	)
+	if m.linkedList != nil { // a zero value map is written as empty snapshot
+		count = m.Len()
+		first = m.linkedList.First()
+	}
+
+	sw := &snapshotWriter{w: w}
+	err := sw.writeHeader(snapshotHeader{
+		version:  snapshotVersion,
+		keyKind:  keyCodec.kind,
+		hasherID: m.hasherID(),
+		count:    uint64(count),
+	})
+	if err != nil {
+		return sw.written, err
+	}
+
+	var record []byte
+	for item := first; item != nil; item = item.Next() {
+		if m.ttl != 0 && m.isExpired(item, nanotime()) {
+			continue
+		}
+
+		record = binary.LittleEndian.AppendUint64(record[:0], uint64(item.keyHash))
+		record = keyCodec.append(record, item.key)
+		record, err = valueCodec.AppendValue(record, item.Value())
+		if err != nil {
+			return sw.written, fmt.Errorf("encoding value of key %v: %w", item.key, err)
+		}
+		if err = sw.writeRecord(record); err != nil {
+			return sw.written, err
+		}
+	}
+
+	return sw.written, sw.close()
+}
+
+// ReadFrom adds all elements of a snapshot that was written by WriteTo to the map and returns
+// the number of bytes read. Existing elements are kept. A zero value map gets initialized.
+// Delta snapshots written by WriteDelta are supported as well, see ApplyDelta.
+// ErrChecksumMismatch is returned if the snapshot is corrupted, elements of blocks that
+// were read before the corruption was detected are added to the map.
+func (m *Map[Key, Value]) ReadFrom(r io.Reader) (int64, error) {
+	keyCodec, _ := newPrimitiveCodec[Key]()
+	valueCodec := m.getValueCodec()
+
+	sr := &snapshotReader{r: r}
+	header, err := sr.readHeader()
+	if err != nil {
+		return sr.read, err
+	}
+	if header.keyKind != keyCodec.kind {
+		return sr.read, fmt.Errorf("snapshot key kind %s does not match map key kind %s", header.keyKind, keyCodec.kind)
+	}
+
+	if header.since == 0 {
+		m.reserve(int(min(header.count, 1<<24))) // limit the pre-allocation for corrupted headers
+	}
+
+	for {
+		records, err := sr.readBlock()
+		if err != nil {
+			return sr.read, err
+		}
+		if records == nil {
+			return sr.read, nil
+		}
+
+		for _, record := range records {
+			key, n, err := keyCodec.decode(record.key)
+			if err != nil || n != len(record.key) {
+				return sr.read, fmt.Errorf("%w: malformed key", errInvalidSnapshot)
+			}
+			if record.deleted {
+				m.Del(key)
+				continue
+			}
+
+			value, err := valueCodec.DecodeValue(record.value)
+			if err != nil {
+				return sr.read, fmt.Errorf("decoding value of key %v: %w", key, err)
+			}
+			m.Set(key, value)
+		}
+	}
+}
+
+// hasherID returns the id of the hasher that is stored in snapshots.
+func (m *Map[Key, Value]) hasherID() uint8 {
+	if m.customHasher {
+		return hasherCustom
+	}
+	return hasherDefault
+}
+
+// snapshotWriter writes the header and blocks of a snapshot.
+type snapshotWriter struct {
+	w       io.Writer
+	block   []byte
+	records uint32
+	written int64
+}
+
+func (sw *snapshotWriter) write(data []byte) error {
+	n, err := sw.w.Write(data)
+	sw.written += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
+
+func (sw *snapshotWriter) writeHeader(header snapshotHeader) error {
+	buf := make([]byte, 0, deltaHeaderSize)
+	buf = append(buf, snapshotMagic...)
+	buf = binary.LittleEndian.AppendUint16(buf, header.version)
+	buf = append(buf, byte(header.keyKind), header.hasherID)
+	buf = binary.LittleEndian.AppendUint64(buf, header.count)
+	if header.version == deltaVersion {
+		buf = binary.LittleEndian.AppendUint64(buf, header.since)
+		buf = binary.LittleEndian.AppendUint64(buf, header.mapVersion)
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+	return sw.write(buf)
+}
+
+// writeRecord adds a record to the current block and writes the block once it is full.
+func (sw *snapshotWriter) writeRecord(record []byte) error {
+	if sw.block == nil {
+		sw.block = make([]byte, 8, 8+snapshotBlockSize) // reserve space for record count and length
+	}
+
+	sw.block = binary.AppendUvarint(sw.block, uint64(len(record)))
+	sw.block = append(sw.block, record...)
+	sw.records++
+
+	if len(sw.block)-8 >= snapshotBlockSize {
+		return sw.flush()
+	}
+	return nil
+}
+
+// flush writes the current block.
+func (sw *snapshotWriter) flush() error {
+	if sw.block == nil {
+		sw.block = make([]byte, 8)
+	}
+
+	binary.LittleEndian.PutUint32(sw.block[0:4], sw.records)
+	binary.LittleEndian.PutUint32(sw.block[4:8], uint32(len(sw.block)-8))
+	sw.block = binary.LittleEndian.AppendUint32(sw.block, crc32.ChecksumIEEE(sw.block))
+
+	err := sw.write(sw.block)
+	sw.block = sw.block[:8]
+	sw.records = 0
+	return err
+}
+
+// close writes the last block if it contains records and the end block.
+func (sw *snapshotWriter) close() error {
+	if sw.records > 0 {
+		if err := sw.flush(); err != nil {
+			return err
+		}
+	}
+	return sw.flush() // empty end block
+}
+
+// snapshotReader reads the header and blocks of a snapshot.
+type snapshotReader struct {
+	r      io.Reader
+	read   int64
+	header snapshotHeader
+}
+
+func (sr *snapshotReader) readFull(buf []byte) error {
+	n, err := io.ReadFull(sr.r, buf)
+	sr.read += int64(n)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+	return nil
+}
+
+func (sr *snapshotReader) readHeader() (snapshotHeader, error) {
+	var header snapshotHeader
+	buf := make([]byte, deltaHeaderSize)
+	if err := sr.readFull(buf[:6]); err != nil {
+		return header, err
+	}
+	if !bytes.Equal(buf[:4], []byte(snapshotMagic)) {
+		return header, fmt.Errorf("%w: unknown file type", errInvalidSnapshot)
+	}
+
+	header.version = binary.LittleEndian.Uint16(buf[4:6])
+	size := snapshotHeaderSize
+	switch header.version {
+	case snapshotVersion:
+	case deltaVersion:
+		size = deltaHeaderSize
+	default:
+		return header, fmt.Errorf("%w %d", errUnsupportedFormat, header.version)
+	}
+
+	buf = buf[:size]
+	if err := sr.readFull(buf[6:]); err != nil {
+		return header, err
+	}
+	if crc32.ChecksumIEEE(buf[:size-4]) != binary.LittleEndian.Uint32(buf[size-4:]) {
+		return header, fmt.Errorf("%w: header", ErrChecksumMismatch)
+	}
+
+	header.keyKind = reflect.Kind(buf[6])
+	header.hasherID = buf[7]
+	header.count = binary.LittleEndian.Uint64(buf[8:16])
+	if header.version == deltaVersion {
+		header.since = binary.LittleEndian.Uint64(buf[16:24])
+		header.mapVersion = binary.LittleEndian.Uint64(buf[24:32])
+	}
+	if _, ok := keyKindWidth(header.keyKind); !ok {
+		return header, fmt.Errorf("%w: unsupported key kind %s", errInvalidSnapshot, header.keyKind)
+	}
+
+	sr.header = header
+	return header, nil
+}
+
+// readBlock reads and verifies the next block and returns its records.
+// It returns nil records for the end block.
+func (sr *snapshotReader) readBlock() ([]snapshotRecord, error) {
+	head := make([]byte, 8)
+	if err := sr.readFull(head); err != nil {
+		return nil, err
+	}
+	count := binary.LittleEndian.Uint32(head[0:4])
+	length := binary.LittleEndian.Uint32(head[4:8])
+	if length > snapshotMaxBlock {
+		return nil, fmt.Errorf("%w: block length %d", errInvalidSnapshot, length)
+	}
+
+	block := make([]byte, 8+int(length)+4)
+	copy(block, head)
+	if err := sr.readFull(block[8:]); err != nil {
+		return nil, err
+	}
+	checksum := binary.LittleEndian.Uint32(block[8+length:])
+	block = block[:8+length]
+	if crc32.ChecksumIEEE(block) != checksum {
+		return nil, fmt.Errorf("%w: block at offset %d", ErrChecksumMismatch, sr.read-int64(len(block))-4)
+	}
+
+	if count == 0 {
+		return nil, nil
+	}
+	return parseRecords(block[8:], count, sr.header)
+}
+
+// parseRecords parses the records of a block.
+func parseRecords(data []byte, count uint32, header snapshotHeader) ([]snapshotRecord, error) {
+	fixedSize := 8
+	if header.version == deltaVersion {
+		fixedSize += 9
+	}
+
+	records := make([]snapshotRecord, 0, count)
+	for range count {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < length || length < uint64(fixedSize) {
+			return nil, fmt.Errorf("%w: malformed record", errInvalidSnapshot)
+		}
+		fields := data[n : n+int(length)]
+		data = data[n+int(length):]
+
+		record := snapshotRecord{
+			hash: binary.LittleEndian.Uint64(fields),
+		}
+		if header.version == deltaVersion {
+			record.version = binary.LittleEndian.Uint64(fields[8:])
+			record.deleted = fields[16]&recordDeleted != 0
+		}
+
+		fields = fields[fixedSize:]
+		keyLength, ok := keyEncodedLength(header.keyKind, fields)
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed key", errInvalidSnapshot)
+		}
+		record.key = fields[:keyLength]
+		record.value = fields[keyLength:]
+		records = append(records, record)
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("%w: trailing block data", errInvalidSnapshot)
+	}
+	return records, nil
+}
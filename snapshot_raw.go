@@ -0,0 +1,251 @@
+package hashmap
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// SnapshotInfo describes the header of a snapshot file.
+type SnapshotInfo struct {
+	Version       int          // format version, 1 for snapshots and 2 for delta snapshots
+	KeyKind       reflect.Kind // kind of the key type of the map
+	DefaultHasher bool         // whether the keys were hashed by the default hasher
+	Count         uint64       // number of elements when the snapshot was started
+	Since         uint64       // version 2 only: map version after which changes are included
+	MapVersion    uint64       // version 2 only: map version that the snapshot covers
+}
+
+// SnapshotEntry is a record of a snapshot file. The key is decoded, the value is kept
+// in the encoding of the value codec of the map that wrote the snapshot.
+type SnapshotEntry struct {
+	Hash    uint64
+	Version uint64 // version 2 only: map version of the last change of the element
+	Deleted bool   // version 2 only: the key was deleted
+	Key     any    // key of the kind of the snapshot, int and uint keys are decoded as int64 and uint64
+	Value   []byte
+}
+
+// SnapshotReader reads the entries of a snapshot file without knowing the value type
+// of the map that wrote it. It is intended for tooling that inspects snapshots.
+type SnapshotReader struct {
+	sr      *snapshotReader
+	records []snapshotRecord
+	done    bool
+}
+
+// NewSnapshotReader reads the header of a snapshot or delta snapshot from r.
+func NewSnapshotReader(r io.Reader) (*SnapshotReader, error) {
+	sr := &snapshotReader{r: r}
+	if _, err := sr.readHeader(); err != nil {
+		return nil, err
+	}
+	return &SnapshotReader{sr: sr}, nil
+}
+
+// Info returns the header information of the snapshot.
+func (r *SnapshotReader) Info() SnapshotInfo {
+	header := r.sr.header
+	return SnapshotInfo{
+		Version:       int(header.version),
+		KeyKind:       header.keyKind,
+		DefaultHasher: header.hasherID == hasherDefault,
+		Count:         header.count,
+		Since:         header.since,
+		MapVersion:    header.mapVersion,
+	}
+}
+
+// BytesRead returns the number of bytes that were read from the underlying reader.
+func (r *SnapshotReader) BytesRead() int64 {
+	return r.sr.read
+}
+
+// Next returns the next entry of the snapshot. It returns io.EOF after the last entry.
+// ErrChecksumMismatch is returned if a block of the snapshot is corrupted.
+func (r *SnapshotReader) Next() (SnapshotEntry, error) {
+	for len(r.records) == 0 {
+		if r.done {
+			return SnapshotEntry{}, io.EOF
+		}
+
+		records, err := r.sr.readBlock()
+		if err != nil {
+			return SnapshotEntry{}, err
+		}
+		if records == nil {
+			r.done = true
+		}
+		r.records = records
+	}
+
+	record := r.records[0]
+	r.records = r.records[1:]
+
+	key, err := decodeRawKey(r.sr.header.keyKind, record.key)
+	if err != nil {
+		return SnapshotEntry{}, err
+	}
+	return SnapshotEntry{
+		Hash:    record.hash,
+		Version: record.version,
+		Deleted: record.deleted,
+		Key:     key,
+		Value:   record.value,
+	}, nil
+}
+
+// SnapshotWriter writes entries to a snapshot file in the format of the given info.
+// Entries are expected in the order of their hashes, followed by deleted entries.
+type SnapshotWriter struct {
+	sw      *snapshotWriter
+	version uint16
+	keyKind reflect.Kind
+	record  []byte
+	key     []byte
+}
+
+// NewSnapshotWriter writes the header of a snapshot that is described by info to w.
+func NewSnapshotWriter(w io.Writer, info SnapshotInfo) (*SnapshotWriter, error) {
+	if info.Version != snapshotVersion && info.Version != deltaVersion {
+		return nil, fmt.Errorf("%w %d", errUnsupportedFormat, info.Version)
+	}
+	if _, ok := keyKindWidth(info.KeyKind); !ok {
+		return nil, fmt.Errorf("%w: unsupported key kind %s", errInvalidSnapshot, info.KeyKind)
+	}
+
+	header := snapshotHeader{
+		version:  uint16(info.Version),
+		keyKind:  info.KeyKind,
+		hasherID: hasherCustom,
+		count:    info.Count,
+	}
+	if info.DefaultHasher {
+		header.hasherID = hasherDefault
+	}
+	if info.Version == deltaVersion {
+		header.since = info.Since
+		header.mapVersion = info.MapVersion
+	}
+
+	sw := &snapshotWriter{w: w}
+	if err := sw.writeHeader(header); err != nil {
+		return nil, err
+	}
+	return &SnapshotWriter{
+		sw:      sw,
+		version: header.version,
+		keyKind: info.KeyKind,
+	}, nil
+}
+
+// Write writes an entry to the snapshot. Deleted entries can only be written to delta snapshots.
+func (w *SnapshotWriter) Write(entry SnapshotEntry) error {
+	if entry.Deleted && w.version != deltaVersion {
+		return fmt.Errorf("%w: deleted entries require format version %d", errUnsupportedFormat, deltaVersion)
+	}
+
+	var err error
+	w.key, err = appendRawKey(w.key[:0], w.keyKind, entry.Key)
+	if err != nil {
+		return err
+	}
+	w.record = appendRecord(w.record[:0], w.version, snapshotRecord{
+		hash:    entry.Hash,
+		version: entry.Version,
+		deleted: entry.Deleted,
+		key:     w.key,
+		value:   entry.Value,
+	})
+	return w.sw.writeRecord(w.record)
+}
+
+// Close writes the pending entries and the end of the snapshot. It does not close
+// the underlying writer.
+func (w *SnapshotWriter) Close() error {
+	return w.sw.close()
+}
+
+// BytesWritten returns the number of bytes that were written to the underlying writer.
+func (w *SnapshotWriter) BytesWritten() int64 {
+	return w.sw.written
+}
+
+// decodeRawKey decodes a key of the given kind.
+func decodeRawKey(kind reflect.Kind, data []byte) (any, error) {
+	switch kind {
+	case reflect.Int, reflect.Int64:
+		return decodeRawKeyOf[int64](data)
+	case reflect.Int8:
+		return decodeRawKeyOf[int8](data)
+	case reflect.Int16:
+		return decodeRawKeyOf[int16](data)
+	case reflect.Int32:
+		return decodeRawKeyOf[int32](data)
+	case reflect.Uint, reflect.Uintptr, reflect.Uint64:
+		return decodeRawKeyOf[uint64](data)
+	case reflect.Uint8:
+		return decodeRawKeyOf[uint8](data)
+	case reflect.Uint16:
+		return decodeRawKeyOf[uint16](data)
+	case reflect.Uint32:
+		return decodeRawKeyOf[uint32](data)
+	case reflect.Float32:
+		return decodeRawKeyOf[float32](data)
+	case reflect.Float64:
+		return decodeRawKeyOf[float64](data)
+	case reflect.String:
+		return decodeRawKeyOf[string](data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported key kind %s", errInvalidSnapshot, kind)
+	}
+}
+
+func decodeRawKeyOf[T any](data []byte) (any, error) {
+	codec, _ := newPrimitiveCodec[T]()
+	key, n, err := codec.decode(data)
+	if err != nil || n != len(data) {
+		return nil, fmt.Errorf("%w: malformed key", errInvalidSnapshot)
+	}
+	return key, nil
+}
+
+// appendRawKey appends the encoding of a key of the given kind to the buffer.
+// The dynamic type of the key has to match the type that decodeRawKey returns for the kind.
+func appendRawKey(buf []byte, kind reflect.Kind, key any) ([]byte, error) {
+	switch kind {
+	case reflect.Int, reflect.Int64:
+		return appendRawKeyOf[int64](buf, key)
+	case reflect.Int8:
+		return appendRawKeyOf[int8](buf, key)
+	case reflect.Int16:
+		return appendRawKeyOf[int16](buf, key)
+	case reflect.Int32:
+		return appendRawKeyOf[int32](buf, key)
+	case reflect.Uint, reflect.Uintptr, reflect.Uint64:
+		return appendRawKeyOf[uint64](buf, key)
+	case reflect.Uint8:
+		return appendRawKeyOf[uint8](buf, key)
+	case reflect.Uint16:
+		return appendRawKeyOf[uint16](buf, key)
+	case reflect.Uint32:
+		return appendRawKeyOf[uint32](buf, key)
+	case reflect.Float32:
+		return appendRawKeyOf[float32](buf, key)
+	case reflect.Float64:
+		return appendRawKeyOf[float64](buf, key)
+	case reflect.String:
+		return appendRawKeyOf[string](buf, key)
+	default:
+		return buf, fmt.Errorf("%w: unsupported key kind %s", errInvalidSnapshot, kind)
+	}
+}
+
+func appendRawKeyOf[T any](buf []byte, key any) ([]byte, error) {
+	k, ok := key.(T)
+	if !ok {
+		return buf, fmt.Errorf("key %v of type %T does not match the key kind of the snapshot", key, key)
+	}
+	codec, _ := newPrimitiveCodec[T]()
+	return codec.append(buf, k), nil
+}
@@ -0,0 +1,71 @@
+package hashmap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestSnapshotReaderWriter(t *testing.T) {
+	t.Parallel()
+	m := New[uint16, string]()
+	for i := range uint16(100) {
+		m.Set(i, "value")
+	}
+
+	var buf bytes.Buffer
+	_, err := m.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	reader, err := NewSnapshotReader(&buf)
+	assert.NoError(t, err)
+	info := reader.Info()
+	assert.Equal(t, 1, info.Version)
+	assert.Equal(t, reflect.Uint16, info.KeyKind)
+	assert.True(t, info.DefaultHasher)
+	assert.Equal(t, 100, info.Count)
+
+	info.Version = deltaVersion
+	var converted bytes.Buffer
+	writer, err := NewSnapshotWriter(&converted, info)
+	assert.NoError(t, err)
+
+	var previous uint64
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.NoError(t, err)
+		assert.True(t, entry.Hash >= previous)
+		previous = entry.Hash
+		assert.NoError(t, writer.Write(entry))
+	}
+	assert.NoError(t, writer.Write(SnapshotEntry{Key: uint16(5), Deleted: true}))
+	assert.NoError(t, writer.Close())
+
+	m2 := New[uint16, string]()
+	_, err = m2.ReadFrom(&converted)
+	assert.NoError(t, err)
+	assert.Equal(t, 99, m2.Len())
+	value, ok := m2.Get(6)
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+}
+
+func TestSnapshotWriterInvalid(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	_, err := NewSnapshotWriter(&buf, SnapshotInfo{Version: 3, KeyKind: reflect.Int})
+	assert.ErrorIs(t, err, errUnsupportedFormat)
+
+	writer, err := NewSnapshotWriter(&buf, SnapshotInfo{Version: 1, KeyKind: reflect.Int})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, writer.Write(SnapshotEntry{Key: int64(1), Deleted: true}), errUnsupportedFormat)
+	assert.True(t, writer.Write(SnapshotEntry{Key: "wrong type"}) != nil)
+	assert.NoError(t, writer.Write(SnapshotEntry{Key: int64(1)}))
+}
@@ -0,0 +1,121 @@
+package hashmap
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	m := New[string, string]()
+	itemCount := 2000
+	padding := strings.Repeat("x", 100) // make the snapshot span multiple blocks
+	for i := range itemCount {
+		m.Set(strconv.Itoa(i), padding+strconv.Itoa(i))
+	}
+
+	var buf bytes.Buffer
+	written, err := m.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, buf.Len(), written)
+	assert.True(t, written > 3*snapshotBlockSize)
+
+	var decoded Map[string, string]
+	read, err := decoded.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, written, read)
+	assert.Equal(t, itemCount, decoded.Len())
+	for i := range itemCount {
+		value, ok := decoded.Get(strconv.Itoa(i))
+		assert.True(t, ok)
+		assert.Equal(t, padding+strconv.Itoa(i), value)
+	}
+}
+
+func TestSnapshotEmpty(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	_, err := New[int, int]().WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshotHeaderSize+12, buf.Len())
+
+	m := New[int, int]()
+	_, err = m.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, m.Len())
+}
+
+func TestSnapshotZeroValue(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	var m Map[string, int]
+	written, err := m.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshotHeaderSize+12, written)
+
+	var decoded Map[string, int]
+	_, err = decoded.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, decoded.Len())
+}
+
+func TestSnapshotCorruption(t *testing.T) {
+	t.Parallel()
+	m := New[int, string]()
+	for i := range 100 {
+		m.Set(i, strconv.Itoa(i))
+	}
+	var buf bytes.Buffer
+	_, err := m.WriteTo(&buf)
+	assert.NoError(t, err)
+	data := buf.Bytes()
+
+	corrupted := bytes.Clone(data)
+	corrupted[snapshotHeaderSize+20]++
+	_, err = New[int, string]().ReadFrom(bytes.NewReader(corrupted))
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	corrupted = bytes.Clone(data)
+	corrupted[8]++ // element count in the header
+	_, err = New[int, string]().ReadFrom(bytes.NewReader(corrupted))
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	_, err = New[int, string]().ReadFrom(bytes.NewReader(data[:len(data)-5]))
+	assert.True(t, err != nil)
+
+	_, err = New[uint, string]().ReadFrom(bytes.NewReader(data))
+	assert.True(t, err != nil) // key kind mismatch
+}
+
+func TestSnapshotConcurrentWrites(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	for i := range 1000 {
+		m.Set(i, i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 1000 {
+			m.Set(i+1000, i)
+			m.Del(i)
+		}
+	}()
+
+	var buf bytes.Buffer
+	_, err := m.WriteTo(&buf)
+	assert.NoError(t, err)
+	wg.Wait()
+
+	decoded := New[int, int]()
+	_, err = decoded.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.True(t, decoded.Len() <= 2000)
+}
@@ -0,0 +1,168 @@
+package hashmap
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultSnapshotInterval is the default interval in which the Snapshotter writes snapshots.
+const defaultSnapshotInterval = time.Minute
+
+// SnapshotterOptions configures a Snapshotter.
+type SnapshotterOptions struct {
+	// Interval is the interval in which snapshots are written, defaults to 1 minute.
+	Interval time.Duration
+
+	// Generations is the number of previous snapshots that are kept, they are
+	// stored with the suffixes ".1" (newest) to ".N" (oldest).
+	Generations int
+
+	// OnError gets called with the error of a failed periodic snapshot.
+	OnError func(err error)
+}
+
+// Snapshotter periodically writes snapshots of a map to a file. A snapshot is written to a
+// temporary file with the suffix ".tmp" first, synced and then renamed over the previous
+// snapshot. Cycles in which the map was not modified are skipped.
+type Snapshotter[Key hashable, Value any] struct {
+	m       *Map[Key, Value]
+	path    string
+	options SnapshotterOptions
+
+	mu          sync.Mutex // serializes snapshots and protects the following fields
+	lastSuccess time.Time
+	lastErr     error
+	lastVersion uint64 // map version of the last successful snapshot
+
+	done      chan struct{}
+	closeOnce sync.Once
+	worker    sync.WaitGroup
+}
+
+// NewSnapshotter returns a new snapshotter that writes snapshots of the map to the path
+// in the background until Close is called. It enables the modification counter of the map,
+// see Map.Version, to detect unmodified maps.
+func NewSnapshotter[Key hashable, Value any](m *Map[Key, Value], path string, options SnapshotterOptions) *Snapshotter[Key, Value] {
+	if options.Interval <= 0 {
+		options.Interval = defaultSnapshotInterval
+	}
+	m.enableVersioning()
+
+	s := &Snapshotter[Key, Value]{
+		m:       m,
+		path:    path,
+		options: options,
+		done:    make(chan struct{}),
+	}
+
+	s.worker.Add(1)
+	go s.run()
+	return s
+}
+
+// Snapshot writes a snapshot immediately unless the map was not modified since the last
+// successful snapshot. It returns whether a snapshot was written.
+func (s *Snapshotter[Key, Value]) Snapshot() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := s.m.Version() // read before writing, changes during the write trigger the next snapshot
+	if !s.lastSuccess.IsZero() && version == s.lastVersion {
+		return false, nil
+	}
+
+	err := s.write()
+	s.lastErr = err
+	if err != nil {
+		return false, err
+	}
+
+	s.lastSuccess = time.Now()
+	s.lastVersion = version
+	return true, nil
+}
+
+// LastSuccess returns the time of the last successful snapshot, it is zero if no snapshot was written yet.
+func (s *Snapshotter[Key, Value]) LastSuccess() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSuccess
+}
+
+// LastError returns the error of the last snapshot attempt, it is nil if the attempt succeeded.
+func (s *Snapshotter[Key, Value]) LastError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+// Close stops the periodic snapshots. It does not write a final snapshot.
+func (s *Snapshotter[Key, Value]) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	s.worker.Wait()
+}
+
+func (s *Snapshotter[Key, Value]) run() {
+	defer s.worker.Done()
+
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.Snapshot(); err != nil && s.options.OnError != nil {
+			s.options.OnError(err)
+		}
+	}
+}
+
+// write writes the snapshot to the temporary file, rotates the previous generations
+// and renames the temporary file over the snapshot path. The snapshot path keeps
+// referencing a complete snapshot at all times.
+func (s *Snapshotter[Key, Value]) write() error {
+	tmp := s.path + ".tmp"
+	if err := writeSyncedFile(tmp, s.m.WriteTo); err != nil {
+		return err
+	}
+
+	if err := s.rotate(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return renameSynced(tmp, s.path)
+}
+
+// rotate moves the previous generations one generation back and links the current snapshot
+// as newest generation, the oldest generation gets replaced. The current snapshot stays in
+// place until the new snapshot replaces it.
+func (s *Snapshotter[Key, Value]) rotate() error {
+	if s.options.Generations <= 0 {
+		return nil
+	}
+
+	for i := s.options.Generations; i > 1; i-- {
+		from := s.path + "." + strconv.Itoa(i-1)
+		to := s.path + "." + strconv.Itoa(i)
+
+		if err := os.Rename(from, to); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("rotating snapshot: %w", err)
+		}
+	}
+
+	if err := linkFile(s.path, s.path+".1"); err != nil {
+		return fmt.Errorf("rotating snapshot: %w", err)
+	}
+	return nil
+}
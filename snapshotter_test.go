@@ -0,0 +1,149 @@
+package hashmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func readSnapshotFile(t *testing.T, path string) *Map[int, int] {
+	t.Helper()
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, file.Close())
+	}()
+
+	m := New[int, int]()
+	_, err = m.ReadFrom(file)
+	assert.NoError(t, err)
+	return m
+}
+
+func TestSnapshotter(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.snapshot")
+	m := New[int, int]()
+	s := NewSnapshotter(m, path, SnapshotterOptions{
+		Interval:    time.Hour,
+		Generations: 2,
+	})
+	defer s.Close()
+
+	for i := range 3 {
+		m.Set(i, i)
+		written, err := s.Snapshot()
+		assert.NoError(t, err)
+		assert.True(t, written)
+	}
+
+	written, err := s.Snapshot() // skipped as the map did not change
+	assert.NoError(t, err)
+	assert.False(t, written)
+	assert.False(t, s.LastSuccess().IsZero())
+	assert.NoError(t, s.LastError())
+
+	assert.Equal(t, 3, readSnapshotFile(t, path).Len())
+	assert.Equal(t, 2, readSnapshotFile(t, path+".1").Len())
+	assert.Equal(t, 1, readSnapshotFile(t, path+".2").Len())
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSnapshotterPeriodic(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.snapshot")
+	m := New[int, int]()
+	m.Set(1, 1)
+	s := NewSnapshotter(m, path, SnapshotterOptions{
+		Interval: time.Millisecond,
+	})
+	defer s.Close()
+
+	waitFor(t, func() bool {
+		return !s.LastSuccess().IsZero()
+	})
+	assert.Equal(t, 1, readSnapshotFile(t, path).Len())
+}
+
+func TestSnapshotterError(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "missing", "map.snapshot")
+	s := NewSnapshotter(New[int, int](), path, SnapshotterOptions{
+		Interval: time.Hour,
+	})
+	defer s.Close()
+
+	_, err := s.Snapshot()
+	assert.True(t, err != nil)
+	assert.Equal(t, err, s.LastError())
+	assert.True(t, s.LastSuccess().IsZero())
+}
+
+func TestMapVersion(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.Set(1, 1)
+	assert.Equal(t, uint64(0), m.Version()) // disabled until a snapshotter is attached
+
+	s := NewSnapshotter(m, filepath.Join(t.TempDir(), "map.snapshot"), SnapshotterOptions{Interval: time.Hour})
+	defer s.Close()
+	version := m.Version()
+
+	m.Set(1, 1)
+	assert.True(t, m.Version() > version)
+	version = m.Version()
+
+	m.Get(1)
+	assert.False(t, m.Insert(1, 2))
+	assert.Equal(t, version, m.Version())
+
+	m.Del(1)
+	assert.True(t, m.Version() > version)
+}
+
+func TestSnapshotterSingleGeneration(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.snapshot")
+	m := New[int, int]()
+	s := NewSnapshotter(m, path, SnapshotterOptions{
+		Interval:    time.Hour,
+		Generations: 1,
+	})
+	defer s.Close()
+
+	for i := range 3 {
+		m.Set(i, i)
+		_, err := s.Snapshot()
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 3, readSnapshotFile(t, path).Len())
+	assert.Equal(t, 2, readSnapshotFile(t, path+".1").Len())
+	_, err := os.Stat(path + ".2")
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path + ".1.tmp")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLinkFileCopy(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	from := filepath.Join(dir, "from")
+	to := filepath.Join(dir, "to")
+	assert.NoError(t, os.WriteFile(from, []byte("data"), 0o644))
+
+	assert.NoError(t, writeSyncedFile(to+".tmp", copyFrom(from)))
+	data, err := os.ReadFile(to + ".tmp")
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	assert.NoError(t, linkFile(filepath.Join(dir, "missing"), to))
+	_, err = os.Stat(to)
+	assert.True(t, os.IsNotExist(err))
+}
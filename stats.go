@@ -0,0 +1,127 @@
+package hashmap
+
+import (
+	"sync/atomic"
+)
+
+const (
+	// statsStripes is the number of counter stripes, it has to be a power of 2.
+	statsStripes = 32
+	// cacheLineSize is the assumed size of a CPU cache line that counter stripes are padded to.
+	cacheLineSize = 64
+)
+
+// Stats contains statistics about a map.
+// The operation counters from Hits on are only collected after calling EnableStats.
+type Stats struct {
+	Len       int    // number of elements within the map
+	IndexSize int    // number of slots of the index
+	FillRate  int    // percentage of filled index slots, see FillRate
+	Weight    int64  // total weight of all elements as reported by the sizer of the memory budget
+	Evictions uint64 // number of elements evicted to stay within the memory budget
+	Resizes   uint64 // number of times that the index was replaced by a resized one
+
+	Counting   bool   // whether the operation counters are collected
+	Hits       uint64 // number of lookups that found the key
+	Misses     uint64 // number of lookups that did not find the key
+	Inserts    uint64 // number of elements that were added
+	Updates    uint64 // number of values of existing elements that were replaced by Set
+	Deletes    uint64 // number of elements that were deleted by Del
+	CASRetries uint64 // number of write operations that had to retry due to concurrent changes
+}
+
+// counterStripe is a set of operation counters that is padded to avoid false sharing
+// with the neighboring stripes.
+type counterStripe struct {
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	inserts    atomic.Uint64
+	updates    atomic.Uint64
+	deletes    atomic.Uint64
+	casRetries atomic.Uint64
+	_          [cacheLineSize - 6*8]byte
+}
+
+// counters spreads the operation counters of a map over stripes that are selected by the
+// key hash, to not let concurrent operations on different keys contend on the same counter.
+type counters struct {
+	stripes [statsStripes]counterStripe
+}
+
+// stripe returns the counter stripe for the hashed key.
+func (c *counters) stripe(hash uintptr) *counterStripe {
+	return &c.stripes[hash&(statsStripes-1)]
+}
+
+// EnableStats enables the collection of the operation counters that Stats returns.
+// It has to be called before the map is used.
+func (m *Map[Key, Value]) EnableStats() {
+	m.counters = &counters{}
+}
+
+// Stats returns a snapshot of the statistics of the map.
+func (m *Map[Key, Value]) Stats() Stats {
+	store := m.store.Load()
+	stats := Stats{
+		Len:       m.Len(),
+		IndexSize: len(store.index),
+		FillRate:  int(store.count.Load()) * 100 / len(store.index),
+		Weight:    m.weight.Load(),
+		Evictions: m.evictions.Load(),
+		Resizes:   m.resizes.Load(),
+	}
+	if m.counters == nil {
+		return stats
+	}
+
+	stats.Counting = true
+	for i := range m.counters.stripes {
+		stripe := &m.counters.stripes[i]
+		stats.Hits += stripe.hits.Load()
+		stats.Misses += stripe.misses.Load()
+		stats.Inserts += stripe.inserts.Load()
+		stats.Updates += stripe.updates.Load()
+		stats.Deletes += stripe.deletes.Load()
+		stats.CASRetries += stripe.casRetries.Load()
+	}
+	return stats
+}
+
+// The following methods count an operation for the hashed key, they do nothing if the
+// counters are disabled.
+
+func (c *counters) hit(hash uintptr) {
+	if c != nil {
+		c.stripe(hash).hits.Add(1)
+	}
+}
+
+func (c *counters) miss(hash uintptr) {
+	if c != nil {
+		c.stripe(hash).misses.Add(1)
+	}
+}
+
+func (c *counters) insert(hash uintptr) {
+	if c != nil {
+		c.stripe(hash).inserts.Add(1)
+	}
+}
+
+func (c *counters) update(hash uintptr) {
+	if c != nil {
+		c.stripe(hash).updates.Add(1)
+	}
+}
+
+func (c *counters) delete(hash uintptr) {
+	if c != nil {
+		c.stripe(hash).deletes.Add(1)
+	}
+}
+
+func (c *counters) casRetry(hash uintptr) {
+	if c != nil {
+		c.stripe(hash).casRetries.Add(1)
+	}
+}
@@ -0,0 +1,79 @@
+package hashmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.EnableStats()
+
+	for i := range 100 {
+		m.Set(i, i)
+	}
+	m.Set(1, 2)
+	assert.True(t, m.Insert(100, 100))
+	assert.False(t, m.Insert(100, 100))
+	_, existed := m.GetOrInsert(101, 101)
+	assert.False(t, existed)
+	_, existed = m.GetOrInsert(101, 101)
+	assert.True(t, existed)
+
+	_, ok := m.Get(1)
+	assert.True(t, ok)
+	_, ok = m.Get(1000)
+	assert.False(t, ok)
+	assert.True(t, m.Del(1))
+	assert.False(t, m.Del(1))
+
+	stats := m.Stats()
+	assert.Equal(t, 101, stats.Len)
+	assert.Equal(t, 102, stats.Inserts)
+	assert.Equal(t, 1, stats.Updates)
+	assert.Equal(t, 2, stats.Hits)
+	assert.Equal(t, 1, stats.Misses)
+	assert.Equal(t, 1, stats.Deletes)
+	waitFor(t, func() bool { // grow runs in the background
+		return m.Stats().Resizes > 0
+	})
+}
+
+func TestStatsDisabled(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.Set(1, 1)
+	m.Get(1)
+
+	stats := m.Stats()
+	assert.Equal(t, 1, stats.Len)
+	assert.Equal(t, 0, stats.Inserts)
+	assert.Equal(t, 0, stats.Hits)
+}
+
+func TestStatsConcurrent(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.EnableStats()
+
+	const goroutines, count = 8, 1000
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range count {
+				m.Set(g*count+i, i)
+				m.Get(g*count + i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := m.Stats()
+	assert.Equal(t, goroutines*count, stats.Inserts)
+	assert.Equal(t, goroutines*count, stats.Hits+stats.Misses)
+}
@@ -1,6 +1,8 @@
 package hashmap
 
 import (
+	"reflect"
+	"strconv"
 	"sync/atomic"
 	"unsafe"
 )
@@ -12,6 +14,18 @@ type store[Key comparable, Value any] struct {
 	index     []*ListElement[Key, Value] // storage for the slice for the garbage collector to not clean it up
 }
 
+// makeStore returns a store with an empty index of the given size, which has to be a power of 2.
+func makeStore[Key comparable, Value any](size uintptr) *store[Key, Value] {
+	index := make([]*ListElement[Key, Value], size)
+	header := (*reflect.SliceHeader)(unsafe.Pointer(&index))
+
+	return &store[Key, Value]{
+		keyShifts: strconv.IntSize - log2(size),
+		array:     unsafe.Pointer(header.Data), // use address of slice data storage
+		index:     index,
+	}
+}
+
 // item returns the item for the given hashed key.
 func (s *store[Key, Value]) item(hashedKey uintptr) *ListElement[Key, Value] {
 	index := hashedKey >> s.keyShifts
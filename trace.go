@@ -0,0 +1,109 @@
+package hashmap
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Operation is the type of a traced map operation.
+type Operation uint8
+
+// Operations that are traced.
+const (
+	OpGet Operation = iota + 1
+	OpSet
+	OpDel
+	OpResize
+)
+
+// String returns the name of the operation.
+func (o Operation) String() string {
+	switch o {
+	case OpGet:
+		return "get"
+	case OpSet:
+		return "set"
+	case OpDel:
+		return "del"
+	case OpResize:
+		return "resize"
+	default:
+		return "unknown"
+	}
+}
+
+// TraceEvent describes a sampled map operation.
+type TraceEvent struct {
+	Op       Operation
+	KeyHash  uintptr       // hashed key, 0 for resizes
+	Walked   int           // number of list elements walked, for resizes the number of elements in the map
+	Retries  int           // number of retries due to concurrent changes
+	Duration time.Duration // duration of the operation
+}
+
+// Tracer receives sampled map operations, for example to forward them to a tracing system.
+// Trace is called synchronously by the goroutine that performed the operation.
+type Tracer interface {
+	Trace(event TraceEvent)
+}
+
+// tracing holds the tracer of a map and its sampling rate.
+type tracing struct {
+	tracer Tracer
+	rate   uint32 // trace one of rate operations
+}
+
+// SetTracer sets a tracer that receives on average one of sampleRate Get, Set, Del and
+// resize operations, a sample rate of 1 traces all operations. A nil tracer disables tracing.
+// It has to be called before the map is used.
+func (m *Map[Key, Value]) SetTracer(tracer Tracer, sampleRate int) {
+	if tracer == nil {
+		m.tracing = nil
+		return
+	}
+	m.tracing = &tracing{
+		tracer: tracer,
+		rate:   uint32(max(sampleRate, 1)),
+	}
+}
+
+// sample returns whether the current operation should be traced.
+func (t *tracing) sample() bool {
+	return t.rate == 1 || rand.Uint32N(t.rate) == 0
+}
+
+func (m *Map[Key, Value]) traceGet(hash uintptr, key Key) (Value, bool) {
+	start := time.Now()
+	value, ok, walked := m.get(hash, key)
+	m.tracing.tracer.Trace(TraceEvent{
+		Op:       OpGet,
+		KeyHash:  hash,
+		Walked:   walked,
+		Duration: time.Since(start),
+	})
+	return value, ok
+}
+
+func (m *Map[Key, Value]) traceSet(hash uintptr, key Key, value Value) {
+	start := time.Now()
+	retries, walked := m.set(hash, key, value)
+	m.tracing.tracer.Trace(TraceEvent{
+		Op:       OpSet,
+		KeyHash:  hash,
+		Walked:   walked,
+		Retries:  retries,
+		Duration: time.Since(start),
+	})
+}
+
+func (m *Map[Key, Value]) traceDel(hash uintptr, key Key) bool {
+	start := time.Now()
+	deleted, walked := m.del(hash, key)
+	m.tracing.tracer.Trace(TraceEvent{
+		Op:       OpDel,
+		KeyHash:  hash,
+		Walked:   walked,
+		Duration: time.Since(start),
+	})
+	return deleted
+}
@@ -0,0 +1,104 @@
+package hashmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+type testTracer struct {
+	mu     sync.Mutex
+	events []TraceEvent
+}
+
+func (t *testTracer) Trace(event TraceEvent) {
+	t.mu.Lock()
+	t.events = append(t.events, event)
+	t.mu.Unlock()
+}
+
+func (t *testTracer) ops(op Operation) []TraceEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var events []TraceEvent
+	for _, event := range t.events {
+		if event.Op == op {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	tracer := &testTracer{}
+	m.SetTracer(tracer, 1)
+
+	for i := range 100 {
+		m.Set(i, i)
+	}
+	value, ok := m.Get(50)
+	assert.True(t, ok)
+	assert.Equal(t, 50, value)
+	_, ok = m.Get(1000)
+	assert.False(t, ok)
+	assert.True(t, m.Del(50))
+
+	sets := tracer.ops(OpSet)
+	assert.Equal(t, 100, len(sets))
+	hash := m.hasher(50)
+	assert.Equal(t, hash, sets[50].KeyHash)
+	assert.Equal(t, 0, sets[0].Walked) // the first element is inserted into an empty list
+	for _, event := range sets[1:] {
+		assert.True(t, event.Walked > 0)
+		assert.Equal(t, 0, event.Retries)
+		assert.True(t, event.Duration >= 0)
+	}
+
+	gets := tracer.ops(OpGet)
+	assert.Equal(t, 2, len(gets))
+	assert.Equal(t, hash, gets[0].KeyHash)
+	assert.True(t, gets[0].Walked > 0)
+
+	dels := tracer.ops(OpDel)
+	assert.Equal(t, 1, len(dels))
+	assert.Equal(t, hash, dels[0].KeyHash)
+	assert.True(t, dels[0].Walked > 0)
+
+	waitFor(t, func() bool { // grow runs in the background
+		return len(tracer.ops(OpResize)) > 0
+	})
+	resize := tracer.ops(OpResize)[0]
+	assert.Equal(t, uintptr(0), resize.KeyHash)
+	assert.True(t, resize.Walked > 0)
+}
+
+func TestTracerSampling(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	tracer := &testTracer{}
+	m.SetTracer(tracer, 10)
+
+	const count = 10000
+	for i := range count {
+		m.Get(i)
+	}
+	gets := len(tracer.ops(OpGet))
+	assert.True(t, gets > 0)
+	assert.True(t, gets < count/2)
+
+	m.SetTracer(nil, 1)
+	m.Get(1)
+	assert.Equal(t, gets, len(tracer.ops(OpGet)))
+}
+
+func TestOperationString(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "get", OpGet.String())
+	assert.Equal(t, "set", OpSet.String())
+	assert.Equal(t, "del", OpDel.String())
+	assert.Equal(t, "resize", OpResize.String())
+	assert.Equal(t, "unknown", Operation(0).String())
+}
@@ -30,3 +30,10 @@ func log2(i uintptr) uintptr {
 	}
 	return n
 }
+
+// indexSizeFor returns the index size that can hold the given number of elements without
+// exceeding the maximum fill rate.
+func indexSizeFor(count int) uintptr {
+	size := roundUpPower2(uintptr(count)*100/maxFillRate + 1)
+	return max(size, defaultSize)
+}
@@ -0,0 +1,103 @@
+package hashmap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes a file by writing to a temporary file with the suffix ".tmp" first,
+// syncing it and renaming it to the final path.
+func writeFileAtomic(path string, write func(io.Writer) (int64, error)) error {
+	tmp := path + ".tmp"
+	if err := writeSyncedFile(tmp, write); err != nil {
+		return err
+	}
+	return renameSynced(tmp, path)
+}
+
+// writeSyncedFile creates a file using the write function and syncs it to stable storage.
+// The file is removed if writing it fails.
+func writeSyncedFile(path string, write func(io.Writer) (int64, error)) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+
+	buffered := bufio.NewWriter(file)
+	_, err = write(buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("writing file: %w", err)
+	}
+	return nil
+}
+
+// renameSynced renames a file and syncs the directory to persist the rename.
+func renameSynced(from, to string) error {
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("renaming file: %w", err)
+	}
+	return syncDir(filepath.Dir(to))
+}
+
+// syncDir syncs a directory to persist a rename of a file within it.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("opening directory: %w", err)
+	}
+	defer func() {
+		_ = d.Close()
+	}()
+
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return fmt.Errorf("syncing directory: %w", err)
+	}
+	return nil
+}
+
+// linkFile makes the file at from also available at to by creating a hard link, which
+// atomically replaces an existing file at to. If the file system does not support hard
+// links, the file is copied. A missing file at from is not an error.
+func linkFile(from, to string) error {
+	tmp := to + ".tmp"
+	_ = os.Remove(tmp) // left over by a previous crash
+
+	if err := os.Link(from, tmp); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err := writeSyncedFile(tmp, copyFrom(from)); err != nil {
+			return err
+		}
+	}
+	return renameSynced(tmp, to)
+}
+
+// copyFrom returns a write function for writeSyncedFile that copies the file at path.
+func copyFrom(path string) func(io.Writer) (int64, error) {
+	return func(w io.Writer) (int64, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("opening file: %w", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		return io.Copy(w, file)
+	}
+}
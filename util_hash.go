@@ -84,6 +84,13 @@ func (m *Map[Key, Value]) setDefaultHasher() {
 	}
 }
 
+// defaultHasher returns the default hasher for the key type, as used by maps.
+func defaultHasher[Key hashable]() func(Key) uintptr {
+	m := &Map[Key, struct{}]{}
+	m.setDefaultHasher()
+	return m.hasher
+}
+
 // Specialized xxhash hash functions, optimized for the bit size of the key where available,
 // for all supported types beside string.
 
@@ -0,0 +1,98 @@
+package hashmap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvariantViolation is returned by Validate if the internal structure of a map is inconsistent.
+var ErrInvariantViolation = errors.New("map invariant violation")
+
+// Validate checks the structural invariants of the map and returns an error wrapping
+// ErrInvariantViolation for the first violation that is found. It is intended for tests and
+// debugging and reports false violations if the map is modified concurrently.
+func (m *Map[Key, Value]) Validate() error {
+	store := m.store.Load()
+	var (
+		live     int
+		nextSlot uintptr // all slots before were verified
+		previous *ListElement[Key, Value]
+		sameHash []Key // keys of the live elements with the hash of the previous element
+	)
+
+	// walk the raw list pointers, Next would unlink deleted elements
+	for element := m.linkedList.head.next.Load(); element != nil; element = element.next.Load() {
+		if previous != nil && element.keyHash < previous.keyHash {
+			return fmt.Errorf("%w: list is not sorted, hash %d follows hash %d",
+				ErrInvariantViolation, element.keyHash, previous.keyHash)
+		}
+		if previous == nil || element.keyHash != previous.keyHash {
+			sameHash = sameHash[:0]
+		}
+		previous = element
+		if element.deleted.Load() != 0 {
+			continue
+		}
+
+		for _, key := range sameHash {
+			if key == element.key {
+				return fmt.Errorf("%w: key %v is contained multiple times", ErrInvariantViolation, key)
+			}
+		}
+		sameHash = append(sameHash, element.key)
+		live++
+
+		slot := element.keyHash >> store.keyShifts
+		if slot < nextSlot {
+			continue // not the first element of the slot
+		}
+		for ; nextSlot < slot; nextSlot++ {
+			if err := validateSlot(store, nextSlot, nil); err != nil {
+				return err
+			}
+		}
+		if err := validateSlot(store, slot, element); err != nil {
+			return err
+		}
+		nextSlot = slot + 1
+	}
+	for ; nextSlot < uintptr(len(store.index)); nextSlot++ {
+		if err := validateSlot(store, nextSlot, nil); err != nil {
+			return err
+		}
+	}
+
+	if count := m.linkedList.Len(); count != live {
+		return fmt.Errorf("%w: list count is %d but %d elements are not deleted", ErrInvariantViolation, count, live)
+	}
+
+	var occupied uintptr
+	for slot := range uintptr(len(store.index)) {
+		if store.item(slot<<store.keyShifts) != nil {
+			occupied++
+		}
+	}
+	if count := store.count.Load(); count != occupied {
+		return fmt.Errorf("%w: index count is %d but %d slots are occupied", ErrInvariantViolation, count, occupied)
+	}
+	return nil
+}
+
+// validateSlot checks that the index slot points at the expected element, which is the
+// first live element that hashes to the slot or nil if there is none.
+func validateSlot[Key comparable, Value any](store *store[Key, Value], slot uintptr, expected *ListElement[Key, Value]) error {
+	element := store.item(slot << store.keyShifts)
+	switch {
+	case element == expected:
+		return nil
+	case element != nil && element.deleted.Load() != 0:
+		return fmt.Errorf("%w: index slot %d points at deleted element with hash %d",
+			ErrInvariantViolation, slot, element.keyHash)
+	case expected == nil:
+		return fmt.Errorf("%w: index slot %d points at element with hash %d but no element hashes to it",
+			ErrInvariantViolation, slot, element.keyHash)
+	default:
+		return fmt.Errorf("%w: index slot %d does not point at its first element with hash %d",
+			ErrInvariantViolation, slot, expected.keyHash)
+	}
+}
@@ -0,0 +1,85 @@
+package hashmap
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	m := New[string, int]()
+	assert.NoError(t, m.Validate())
+
+	for i := range 1000 {
+		m.Set(strconv.Itoa(i), i)
+	}
+	for i := 0; i < 1000; i += 3 {
+		m.Del(strconv.Itoa(i))
+	}
+	waitFor(t, func() bool {
+		return m.resizing.Load() == 0
+	})
+	assert.NoError(t, m.Validate())
+}
+
+func TestValidateConcurrentWrites(t *testing.T) {
+	t.Parallel()
+	m := New[int, int]()
+	m.SetHasher(func(key int) uintptr {
+		return uintptr(key % 64) // force collisions
+	})
+
+	var wg sync.WaitGroup
+	for g := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				key := g*500 + i
+				m.Set(key, key)
+				if i%2 == 0 {
+					m.Del(key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	waitFor(t, func() bool {
+		return m.resizing.Load() == 0
+	})
+	assert.NoError(t, m.Validate())
+	assert.Equal(t, 1000, m.Len())
+}
+
+func TestValidateViolations(t *testing.T) {
+	t.Parallel()
+	newMap := func() *Map[int, int] {
+		m := New[int, int]()
+		for i := range 5 {
+			m.Set(i, i)
+		}
+		return m
+	}
+
+	m := newMap()
+	m.linkedList.count.Add(1)
+	assert.ErrorIs(t, m.Validate(), ErrInvariantViolation)
+
+	m = newMap()
+	m.store.Load().count.Add(1)
+	assert.ErrorIs(t, m.Validate(), ErrInvariantViolation)
+
+	m = newMap()
+	first := m.linkedList.First()
+	first.keyHash = ^uintptr(0) // breaks the sort order
+	assert.ErrorIs(t, m.Validate(), ErrInvariantViolation)
+
+	m = newMap()
+	element := m.linkedList.First()
+	element.deleted.Store(1) // deleted but still referenced by the index
+	m.linkedList.count.Add(^uintptr(0))
+	assert.ErrorIs(t, m.Validate(), ErrInvariantViolation)
+}
@@ -0,0 +1,387 @@
+package hashmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// SyncPolicy defines when the write-ahead log gets synced to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs the log after every mutation before the mutating call returns.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval syncs the log in the interval of WALOptions.SyncInterval.
+	SyncInterval
+	// SyncNever leaves syncing the log to the operating system.
+	SyncNever
+)
+
+// defaultSyncInterval is the default interval for the SyncInterval policy.
+const defaultSyncInterval = time.Second
+
+// Write-ahead log record format
+//
+//	checksum    uint32  CRC32 (IEEE) of the payload
+//	length      uint32  byte length of the payload
+//	payload:
+//	  operation uint8   walOpSet or walOpDel
+//	  key       fixed width numeric or uvarint length prefixed string
+//	  value     []byte  value encoded by the value codec of the map, only for walOpSet
+const (
+	walRecordHeaderSize = 8
+	walOpSet            = 1
+	walOpDel            = 2
+)
+
+var errWALClosed = errors.New("write-ahead log is closed")
+
+// WALOptions configures a write-ahead log.
+type WALOptions struct {
+	// Sync defines when the log gets synced to stable storage.
+	Sync SyncPolicy
+	// SyncInterval is the interval for the SyncInterval policy, defaults to 1 second.
+	SyncInterval time.Duration
+}
+
+// WAL makes the mutations of a map durable by recording them in an append-only log file.
+// All mutations have to be done through the WAL, they are serialized by it. Reads can use
+// the map directly.
+// The log can be compacted into a snapshot file that is stored next to the log with the
+// suffix ".snapshot".
+type WAL[Key hashable, Value any] struct {
+	m       *Map[Key, Value]
+	path    string
+	options WALOptions
+
+	keyCodec   primitiveCodec[Key]
+	valueCodec ValueCodec[Value]
+
+	mu     sync.Mutex // protects all following fields and serializes mutations
+	file   *os.File
+	record []byte
+	size   int64 // offset after the last complete record
+	torn   bool  // marks the log as containing data of a failed write after size
+	dirty  bool  // marks the log as written since the last sync
+
+	done      chan struct{}
+	closeOnce sync.Once
+	syncer    sync.WaitGroup
+}
+
+// OpenWAL opens the write-ahead log at the given path and replays the snapshot and log into
+// the map. A torn or corrupted record at the end of the log, as left behind by a crash
+// during a write, and all data following it gets discarded.
+func OpenWAL[Key hashable, Value any](m *Map[Key, Value], path string, options WALOptions) (*WAL[Key, Value], error) {
+	keyCodec, _ := newPrimitiveCodec[Key]()
+	w := &WAL[Key, Value]{
+		m:          m,
+		path:       path,
+		options:    options,
+		keyCodec:   keyCodec,
+		valueCodec: m.getValueCodec(),
+		done:       make(chan struct{}),
+	}
+
+	if err := w.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening log file: %w", err)
+	}
+	w.file = file
+
+	if err := w.replay(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	if options.Sync == SyncInterval {
+		if w.options.SyncInterval <= 0 {
+			w.options.SyncInterval = defaultSyncInterval
+		}
+		w.syncer.Add(1)
+		go w.syncLoop()
+	}
+	return w, nil
+}
+
+// Map returns the map that the log records the mutations for.
+func (w *WAL[Key, Value]) Map() *Map[Key, Value] {
+	return w.m
+}
+
+// Get retrieves an element from the map under given key.
+func (w *WAL[Key, Value]) Get(key Key) (Value, bool) {
+	return w.m.Get(key)
+}
+
+// Set records the value under the key in the log and sets it in the map.
+// The map is not modified if writing the log fails.
+func (w *WAL[Key, Value]) Set(key Key, value Value) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.append(walOpSet, key, value); err != nil {
+		return err
+	}
+	w.m.Set(key, value)
+	return nil
+}
+
+// Insert sets the value under the key if it does not exist yet and records it in the log.
+// Returns true if the item was inserted or false if it existed.
+func (w *WAL[Key, Value]) Insert(key Key, value Value) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.m.Get(key); ok {
+		return false, nil
+	}
+	if err := w.append(walOpSet, key, value); err != nil {
+		return false, err
+	}
+	return w.m.Insert(key, value), nil
+}
+
+// Del records the deletion of the key in the log and deletes it from the map.
+// Returns whether the key was deleted.
+func (w *WAL[Key, Value]) Del(key Key) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.m.Get(key); !ok {
+		return false, nil
+	}
+	if err := w.append(walOpDel, key, *new(Value)); err != nil {
+		return false, err
+	}
+	return w.m.Del(key), nil
+}
+
+// Sync syncs the log to stable storage.
+func (w *WAL[Key, Value]) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sync()
+}
+
+// Compact writes a snapshot of the map next to the log and truncates the log that is
+// covered by the snapshot. Mutations are blocked while the snapshot is written.
+func (w *WAL[Key, Value]) Compact() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return errWALClosed
+	}
+
+	snapshotPath := w.path + ".snapshot"
+	if err := writeFileAtomic(snapshotPath, w.m.WriteTo); err != nil {
+		return err
+	}
+
+	// a crash before the truncation replays the log on top of the snapshot,
+	// which results in the same state as the log only contains absolute operations
+	if err := w.truncate(0); err != nil {
+		return err
+	}
+	w.dirty = true
+	return w.sync()
+}
+
+// Close syncs and closes the log.
+func (w *WAL[Key, Value]) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	w.syncer.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	w.dirty = true
+	err := w.sync()
+	if closeErr := w.file.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("closing log file: %w", closeErr)
+	}
+	w.file = nil
+	return err
+}
+
+// append writes a record for the operation to the log. If writing or syncing the record
+// fails, the log is truncated to the end of the previous record, so that neither a torn
+// record hides the following records on replay nor a failed write gets replayed.
+func (w *WAL[Key, Value]) append(op byte, key Key, value Value) error {
+	if w.file == nil {
+		return errWALClosed
+	}
+	if w.torn {
+		if err := w.truncate(w.size); err != nil {
+			return err
+		}
+	}
+
+	record := append(w.record[:0], make([]byte, walRecordHeaderSize)...)
+	record = append(record, op)
+	record = w.keyCodec.append(record, key)
+	if op == walOpSet {
+		var err error
+		record, err = w.valueCodec.AppendValue(record, value)
+		if err != nil {
+			return fmt.Errorf("encoding value of key %v: %w", key, err)
+		}
+	}
+	w.record = record
+
+	payload := record[walRecordHeaderSize:]
+	binary.LittleEndian.PutUint32(record[0:4], crc32.ChecksumIEEE(payload))
+	binary.LittleEndian.PutUint32(record[4:8], uint32(len(payload)))
+
+	if _, err := w.file.Write(record); err != nil {
+		return w.rollback(fmt.Errorf("writing log file: %w", err))
+	}
+	w.dirty = true
+
+	if w.options.Sync == SyncAlways {
+		if err := w.sync(); err != nil {
+			return w.rollback(err)
+		}
+	}
+	w.size += int64(len(record))
+	return nil
+}
+
+// rollback removes the data of a failed write from the log and returns the error of the write.
+// If the truncation fails, it is retried before the next record is written.
+func (w *WAL[Key, Value]) rollback(err error) error {
+	w.torn = true
+	if truncateErr := w.truncate(w.size); truncateErr != nil {
+		return errors.Join(err, truncateErr)
+	}
+	return err
+}
+
+// truncate truncates the log to the given size and moves the write offset to its end.
+func (w *WAL[Key, Value]) truncate(size int64) error {
+	if err := w.file.Truncate(size); err != nil {
+		return fmt.Errorf("truncating log file: %w", err)
+	}
+	if _, err := w.file.Seek(size, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking log file: %w", err)
+	}
+	w.size = size
+	w.torn = false
+	return nil
+}
+
+func (w *WAL[Key, Value]) sync() error {
+	if !w.dirty || w.file == nil {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("syncing log file: %w", err)
+	}
+	w.dirty = false
+	return nil
+}
+
+func (w *WAL[Key, Value]) syncLoop() {
+	defer w.syncer.Done()
+
+	ticker := time.NewTicker(w.options.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			_ = w.Sync() // a failed sync is retried in the next interval and on close
+		}
+	}
+}
+
+// loadSnapshot reads the snapshot of a previous compaction into the map.
+func (w *WAL[Key, Value]) loadSnapshot() error {
+	file, err := os.Open(w.path + ".snapshot")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("opening snapshot file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if _, err := w.m.ReadFrom(bufio.NewReader(file)); err != nil {
+		return fmt.Errorf("reading snapshot file: %w", err)
+	}
+	return nil
+}
+
+// replay applies all records of the log to the map and truncates the log after the
+// last valid record.
+func (w *WAL[Key, Value]) replay() error {
+	reader := bufio.NewReader(w.file)
+	var offset int64
+	header := make([]byte, walRecordHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break // end of log or torn record header
+		}
+		checksum := binary.LittleEndian.Uint32(header[0:4])
+		length := binary.LittleEndian.Uint32(header[4:8])
+		if length == 0 || length > snapshotMaxBlock {
+			break // corrupted record header
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			break // torn record
+		}
+		if crc32.ChecksumIEEE(payload) != checksum || !w.apply(payload) {
+			break // corrupted record
+		}
+		offset += walRecordHeaderSize + int64(length)
+	}
+
+	return w.truncate(offset)
+}
+
+// apply applies the operation of a record payload to the map and returns whether the payload was valid.
+func (w *WAL[Key, Value]) apply(payload []byte) bool {
+	key, n, err := w.keyCodec.decode(payload[1:])
+	if err != nil {
+		return false
+	}
+
+	switch payload[0] {
+	case walOpSet:
+		value, err := w.valueCodec.DecodeValue(payload[1+n:])
+		if err != nil {
+			return false
+		}
+		w.m.Set(key, value)
+	case walOpDel:
+		w.m.Del(key)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package hashmap
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cornelk/hashmap/assert"
+)
+
+func TestWALReplay(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.log")
+
+	w, err := OpenWAL(New[string, int](), path, WALOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, w.Set("a", 1))
+	assert.NoError(t, w.Set("b", 2))
+	assert.NoError(t, w.Set("a", 3))
+	inserted, err := w.Insert("c", 4)
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+	inserted, err = w.Insert("c", 5)
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	deleted, err := w.Del("b")
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	assert.NoError(t, w.Close())
+
+	w, err = OpenWAL(New[string, int](), path, WALOptions{Sync: SyncNever})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, w.Close())
+	}()
+
+	assert.Equal(t, 2, w.Map().Len())
+	value, ok := w.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+	value, ok = w.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 4, value)
+}
+
+func TestWALTornTail(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.log")
+
+	w, err := OpenWAL(New[int, string](), path, WALOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, w.Set(1, "one"))
+	assert.NoError(t, w.Set(2, "two"))
+	assert.NoError(t, w.Close())
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(path, info.Size()-2)) // simulate a torn write of the last record
+
+	w, err = OpenWAL(New[int, string](), path, WALOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, w.Map().Len())
+	_, ok := w.Get(2)
+	assert.False(t, ok)
+
+	assert.NoError(t, w.Set(3, "three")) // appends after the discarded record
+	assert.NoError(t, w.Close())
+
+	w, err = OpenWAL(New[int, string](), path, WALOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, w.Map().Len())
+	value, ok := w.Get(3)
+	assert.True(t, ok)
+	assert.Equal(t, "three", value)
+	assert.NoError(t, w.Close())
+}
+
+func TestWALCompact(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.log")
+
+	w, err := OpenWAL(New[int, int](), path, WALOptions{Sync: SyncInterval, SyncInterval: time.Millisecond})
+	assert.NoError(t, err)
+	for i := range 100 {
+		assert.NoError(t, w.Set(i, i))
+	}
+	assert.NoError(t, w.Compact())
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, info.Size())
+
+	_, err = w.Del(0)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	w, err = OpenWAL(New[int, int](), path, WALOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 99, w.Map().Len())
+	value, ok := w.Get(99)
+	assert.True(t, ok)
+	assert.Equal(t, 99, value)
+	assert.NoError(t, w.Close())
+
+	assert.ErrorIs(t, w.Set(1, 1), errWALClosed)
+}
+
+func TestWALFailedWrite(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.log")
+
+	w, err := OpenWAL(New[int, string](), path, WALOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, w.Set(1, "one"))
+
+	w.mu.Lock()
+	_, err = w.file.Write([]byte{1, 2, 3}) // simulate a short write that could not be rolled back
+	assert.NoError(t, err)
+	w.torn = true
+	w.mu.Unlock()
+
+	assert.NoError(t, w.Set(2, "two"))
+	assert.NoError(t, w.Close())
+
+	w, err = OpenWAL(New[int, string](), path, WALOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, w.Map().Len())
+	value, ok := w.Get(2)
+	assert.True(t, ok)
+	assert.Equal(t, "two", value)
+	assert.NoError(t, w.Close())
+}
+
+func TestWALConcurrentClose(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "map.log")
+
+	w, err := OpenWAL(New[int, int](), path, WALOptions{Sync: SyncInterval})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, w.Close())
+		}()
+	}
+	wg.Wait()
+}